@@ -9,8 +9,8 @@ import (
 
 var cmd = &cli.Command{
 	Name:     "yt-transcribe",
-	Usage:    "Transcribe YouTube videos using AI speech recognition",
-	Commands: []*cli.Command{versionCmd, transcribeCmd, runserverCmd},
+	Usage:    "Transcribe videos using AI speech recognition",
+	Commands: []*cli.Command{versionCmd, transcribeCmd, runserverCmd, subscriptionsCmd},
 }
 
 func Run() error {
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/exler/yt-transcribe/internal/export"
+	"github.com/exler/yt-transcribe/internal/fetch"
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// outputOptions controls where and in which format the transcribe command writes its results.
+type outputOptions struct {
+	format export.Format
+	// File to write the result to instead of stdout.
+	outputPath string
+	// Directory to write the transcript and summary to as sibling files.
+	outputDir string
+	// Template for the base name of files written to outputDir.
+	filenameTemplate *template.Template
+}
+
+func newOutputOptions(formatName, outputPath, outputDir, filenameTemplate string) (outputOptions, error) {
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		return outputOptions{}, err
+	}
+
+	if outputPath != "" && outputDir != "" {
+		return outputOptions{}, errors.New("--output and --output-dir cannot be used together")
+	}
+
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(filenameTemplate)
+	if err != nil {
+		return outputOptions{}, fmt.Errorf("invalid filename template: %w", err)
+	}
+
+	return outputOptions{
+		format:           format,
+		outputPath:       outputPath,
+		outputDir:        outputDir,
+		filenameTemplate: tmpl,
+	}, nil
+}
+
+// validate checks that the requested output can be produced.
+func (o outputOptions) validate(summarize bool) error {
+	// Without an output directory only the summary is written when summarizing
+	if summarize && o.outputDir == "" && !o.format.SupportsSummary() {
+		return fmt.Errorf("summaries cannot be written as %q, use one of %v", o.format, export.SummaryFormats)
+	}
+	return nil
+}
+
+// isPlainStdout reports whether the output is written to stdout as plain text,
+// so a summary can be printed as it is written instead of by write.
+func (o outputOptions) isPlainStdout() bool {
+	return o.outputPath == "" && o.outputDir == "" && o.format == export.FormatText
+}
+
+// write outputs the transcript, or the summary when summarizing, to stdout or the output file.
+// With an output directory both the transcript and the summary are written as sibling files.
+func (o outputOptions) write(metadata fetch.VideoMetadata, t transcript.Transcript, summary string, summarize bool) error {
+	if o.outputDir != "" {
+		return o.writeToDir(metadata, t, summary, summarize)
+	}
+
+	var data []byte
+	var err error
+	if summarize {
+		data, err = export.Summary(summary, metadata.Title, o.format)
+	} else {
+		data, err = export.Transcript(t, metadata.Title, o.format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.outputPath == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(o.outputPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", o.outputPath)
+	return nil
+}
+
+func (o outputOptions) writeToDir(metadata fetch.VideoMetadata, t transcript.Transcript, summary string, summarize bool) error {
+	var name bytes.Buffer
+	if err := o.filenameTemplate.Execute(&name, metadata); err != nil {
+		return fmt.Errorf("failed to render filename template: %w", err)
+	}
+	basePath := filepath.Join(o.outputDir, export.SanitizeFilename(name.String()))
+
+	if err := os.MkdirAll(o.outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	data, err := export.Transcript(t, metadata.Title, o.format)
+	if err != nil {
+		return err
+	}
+	transcriptPath := fmt.Sprintf("%s.%s", basePath, o.format)
+	if err := os.WriteFile(transcriptPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write transcript file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", transcriptPath)
+
+	if !summarize {
+		return nil
+	}
+
+	// Fall back to plain text for formats that only make sense for transcripts, e.g. subtitles
+	summaryFormat := o.format
+	if !summaryFormat.SupportsSummary() {
+		summaryFormat = export.FormatText
+	}
+	data, err = export.Summary(summary, metadata.Title, summaryFormat)
+	if err != nil {
+		return err
+	}
+	summaryPath := fmt.Sprintf("%s.summary.%s", basePath, summaryFormat)
+	if err := os.WriteFile(summaryPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", summaryPath)
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/ffmpeg"
+)
+
+const (
+	progressBarWidth    = 30
+	progressBarInterval = 200 * time.Millisecond
+)
+
+// progressBar draws the progress of a transcription on a terminal, redrawing a single line.
+type progressBar struct {
+	out   *os.File
+	last  time.Time
+	drawn bool
+}
+
+// newProgressBar returns a progress bar drawn to out, or nil if out is not a terminal.
+func newProgressBar(out *os.File) *progressBar {
+	if !isTerminal(out) {
+		return nil
+	}
+	return &progressBar{out: out}
+}
+
+// isTerminal reports whether the file is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Update redraws the bar, at most every progressBarInterval until the transcription is done.
+func (b *progressBar) Update(p ffmpeg.Progress) {
+	percent := p.Percent()
+	if time.Since(b.last) < progressBarInterval && percent < 100 {
+		return
+	}
+	b.last = time.Now()
+
+	filled := int(percent / 100 * progressBarWidth)
+	line := fmt.Sprintf("\r[%s%s] %5.1f%%", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), percent)
+	if eta := p.ETA(); eta > 0 {
+		line += "  ETA " + eta.Round(time.Second).String()
+	}
+	// Pad to clear the rest of a longer previous line
+	fmt.Fprintf(b.out, "%-60s", line)
+	b.drawn = true
+}
+
+// Finish ends the line of the bar, so following output starts on a new line.
+func (b *progressBar) Finish() {
+	if b.drawn {
+		fmt.Fprintln(b.out)
+	}
+}
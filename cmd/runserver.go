@@ -2,18 +2,31 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/exler/yt-transcribe/internal/fetch"
 	internalHttp "github.com/exler/yt-transcribe/internal/http"
+	"github.com/exler/yt-transcribe/internal/llm"
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/queue"
+	"github.com/exler/yt-transcribe/internal/subscription"
+	"github.com/exler/yt-transcribe/internal/transcript"
 	"github.com/urfave/cli/v3"
 )
 
 var runserverCmd = &cli.Command{
 	Name:  "runserver",
-	Usage: "Start HTTP server for YouTube transcription and queue management",
+	Usage: "Start HTTP server for video transcription and queue management",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:    "llm-endpoint",
@@ -33,6 +46,30 @@ var runserverCmd = &cli.Command{
 			Value:   "phi3:mini",
 			Sources: cli.EnvVars("LLM_MODEL"),
 		},
+		&cli.IntFlag{
+			Name:    "llm-chunk-tokens",
+			Usage:   "Approximate number of transcript tokens per LLM request, longer transcripts are summarized in parts",
+			Value:   llm.DefaultChunkTokens,
+			Sources: cli.EnvVars("LLM_CHUNK_TOKENS"),
+		},
+		&cli.IntFlag{
+			Name:    "llm-chunk-overlap",
+			Usage:   "Approximate number of tokens each part of a long transcript repeats from the previous one",
+			Value:   llm.DefaultChunkOverlap,
+			Sources: cli.EnvVars("LLM_CHUNK_OVERLAP"),
+		},
+		&cli.StringFlag{
+			Name:    "summary-style",
+			Usage:   "Prompt template to summarize with, e.g. 'default', 'tldr', 'bullets', 'minutes', 'study-notes' or a template from --prompt-dir",
+			Value:   llm.DefaultStyle,
+			Sources: cli.EnvVars("SUMMARY_STYLE"),
+		},
+		&cli.StringFlag{
+			Name:    "prompt-dir",
+			Usage:   "Directory with additional summary prompt templates (<style>.tmpl Go templates, replacing built-in styles of the same name)",
+			Value:   "",
+			Sources: cli.EnvVars("PROMPT_DIR"),
+		},
 		&cli.StringFlag{
 			Name:    "whisper-model-path",
 			Usage:   "Path to ggml whisper.cpp model file",
@@ -51,6 +88,99 @@ var runserverCmd = &cli.Command{
 			Value:   15,
 			Sources: cli.EnvVars("WHISPER_QUEUE"),
 		},
+		&cli.StringFlag{
+			Name:    "yt-dlp-path",
+			Usage:   "Path to the yt-dlp binary",
+			Value:   "yt-dlp",
+			Sources: cli.EnvVars("YT_DLP_PATH"),
+		},
+		&cli.StringFlag{
+			Name:    "ffmpeg-path",
+			Usage:   "Path to the ffmpeg binary, which must be built with the whisper filter",
+			Value:   "ffmpeg",
+			Sources: cli.EnvVars("FFMPEG_PATH"),
+		},
+		&cli.StringFlag{
+			Name:    "captions",
+			Usage:   "Default transcript source: 'whisper' to always transcribe, 'manual' to use uploaded captions when available or 'auto' to also accept automatic captions",
+			Value:   string(transcript.CaptionPolicyWhisper),
+			Sources: cli.EnvVars("CAPTIONS"),
+		},
+		&cli.StringFlag{
+			Name:    "data-dir",
+			Usage:   "Directory to persist the transcription queue in. Leave empty to keep the queue in memory only.",
+			Value:   "",
+			Sources: cli.EnvVars("DATA_DIR"),
+		},
+		&cli.StringFlag{
+			Name:    "upload-dir",
+			Usage:   "Directory to store uploaded files in (defaults to 'uploads' in the data directory, or a temporary directory)",
+			Value:   "",
+			Sources: cli.EnvVars("UPLOAD_DIR"),
+		},
+		&cli.IntFlag{
+			Name:    "max-upload-size",
+			Usage:   "Maximum size of an uploaded file in megabytes",
+			Value:   2048,
+			Sources: cli.EnvVars("MAX_UPLOAD_SIZE"),
+		},
+		&cli.StringFlag{
+			Name:    "recovery-policy",
+			Usage:   "What to do with jobs interrupted by a restart: 'requeue' to process them again or 'fail' to mark them as failed",
+			Value:   string(queue.RecoveryPolicyRequeue),
+			Sources: cli.EnvVars("RECOVERY_POLICY"),
+		},
+		&cli.IntFlag{
+			Name:    "workers",
+			Usage:   "Number of videos processed at the same time",
+			Value:   1,
+			Sources: cli.EnvVars("WORKERS"),
+		},
+		&cli.IntFlag{
+			Name:    "max-downloads",
+			Usage:   "Maximum number of concurrent yt-dlp downloads (0 to only limit by workers)",
+			Value:   3,
+			Sources: cli.EnvVars("MAX_DOWNLOADS"),
+		},
+		&cli.IntFlag{
+			Name:    "max-transcriptions",
+			Usage:   "Maximum number of concurrent ffmpeg whisper transcriptions (0 to only limit by workers)",
+			Value:   1,
+			Sources: cli.EnvVars("MAX_TRANSCRIPTIONS"),
+		},
+		&cli.IntFlag{
+			Name:    "max-summaries",
+			Usage:   "Maximum number of concurrent LLM summarization requests (0 to only limit by workers)",
+			Value:   3,
+			Sources: cli.EnvVars("MAX_SUMMARIES"),
+		},
+		&cli.DurationFlag{
+			Name:    "metadata-timeout",
+			Usage:   "Time limit for fetching the metadata of a video (0 for no limit)",
+			Value:   5 * time.Minute,
+			Sources: cli.EnvVars("METADATA_TIMEOUT"),
+		},
+		&cli.DurationFlag{
+			Name:    "download-timeout",
+			Usage:   "Time limit for downloading the captions or audio of a video (0 for no limit)",
+			Sources: cli.EnvVars("DOWNLOAD_TIMEOUT"),
+		},
+		&cli.DurationFlag{
+			Name:    "transcription-timeout",
+			Usage:   "Time limit for transcribing a video with the whisper filter (0 for no limit)",
+			Sources: cli.EnvVars("TRANSCRIPTION_TIMEOUT"),
+		},
+		&cli.DurationFlag{
+			Name:    "summary-timeout",
+			Usage:   "Time limit for summarizing a transcript with the LLM (0 for no limit)",
+			Sources: cli.EnvVars("SUMMARY_TIMEOUT"),
+		},
+		&cli.DurationFlag{
+			Name:    "subscription-interval",
+			Usage:   "How often to check subscribed channels and playlists for new videos (0 to disable)",
+			Value:   30 * time.Minute,
+			Sources: cli.EnvVars("SUBSCRIPTION_INTERVAL"),
+		},
 		&cli.IntFlag{
 			Name:  "port",
 			Usage: "Port to run the HTTP server on",
@@ -64,15 +194,102 @@ var runserverCmd = &cli.Command{
 		whisperModelPath := cmd.String("whisper-model-path")
 		whisperLanguage := cmd.String("whisper-language")
 		whisperQueueSize := cmd.Int("whisper-queue")
+		dataDir := cmd.String("data-dir")
+
+		recoveryPolicy, err := queue.ParseRecoveryPolicy(cmd.String("recovery-policy"))
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		captionPolicy, err := transcript.ParseCaptionPolicy(cmd.String("captions"))
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
 
-		server, err := internalHttp.NewServer()
+		styles, err := llm.LoadStyles(cmd.String("prompt-dir"))
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		var subscriptionStore subscription.Store = &subscription.MemoryStore{}
+		if dataDir != "" {
+			store, err := queue.NewFileStore(dataDir)
+			if err != nil {
+				return cli.Exit("Failed to initialize queue storage: "+err.Error(), 1)
+			}
+			if err := queue.Open(store); err != nil {
+				return cli.Exit("Failed to load queue: "+err.Error(), 1)
+			}
+			log.Printf("Loaded %d queue entries from %s", len(queue.GetAll()), dataDir)
+
+			if subscriptionStore, err = subscription.NewFileStore(dataDir); err != nil {
+				return cli.Exit("Failed to initialize subscription storage: "+err.Error(), 1)
+			}
+		}
+
+		downloader, err := fetch.NewDownloader("") // OutputDir not used by ListPlaylist
+		if err != nil {
+			return cli.Exit("Failed to initialize downloader: "+err.Error(), 1)
+		}
+		downloader.Binary = cmd.String("yt-dlp-path")
+		subscriptions := subscription.NewManager(subscriptionStore)
+		subscriptionInterval := cmd.Duration("subscription-interval")
+		poller := subscription.NewPoller(subscriptions, downloader, subscriptionInterval)
+
+		uploadDir := cmd.String("upload-dir")
+		if uploadDir == "" {
+			if dataDir != "" {
+				uploadDir = filepath.Join(dataDir, "uploads")
+			} else {
+				uploadDir = filepath.Join(os.TempDir(), "yt-transcribe-uploads")
+			}
+		}
+
+		// Questions are answered right away, so the server needs its own client
+		var answerer llm.Answerer
+		if llmEndpoint != "" {
+			answerer, err = llm.NewAnswerer(llm.Config{
+				Endpoint:     llmEndpoint,
+				Token:        llmToken,
+				Model:        llmModel,
+				ChunkTokens:  cmd.Int("llm-chunk-tokens"),
+				ChunkOverlap: cmd.Int("llm-chunk-overlap"),
+			})
+			if err != nil {
+				return cli.Exit("Failed to initialize question answering: "+err.Error(), 1)
+			}
+		}
+
+		server, err := internalHttp.NewServer(internalHttp.ServerConfig{
+			Subscriptions: subscriptions,
+			Poller:        poller,
+			UploadDir:     uploadDir,
+			MaxUploadSize: int64(cmd.Int("max-upload-size")) << 20,
+			Styles:        styles,
+			Summarize:     llmEndpoint != "",
+			Answerer:      answerer,
+			YTDLPPath:     cmd.String("yt-dlp-path"),
+		})
 		if err != nil {
 			return cli.Exit("Failed to initialize server: "+err.Error(), 1)
 		}
 
 		http.HandleFunc("/", server.IndexHandler)
+		http.HandleFunc("/upload", server.UploadHandler)
 		http.HandleFunc("/queue", server.QueueDataHandler)
-		http.HandleFunc("/entry/{videoID}", server.EntryHandler)
+		http.HandleFunc("/entry/{id}", server.EntryHandler)
+		http.HandleFunc("/entry/{id}/status", server.EntryStatusHandler)
+		http.HandleFunc("/entry/{id}/events", server.EntryEventsHandler)
+		http.HandleFunc("/entry/{id}/cancel", server.CancelHandler)
+		http.HandleFunc("/entry/{id}/retry", server.RetryHandler)
+		http.HandleFunc("/entry/{id}/remove", server.RemoveHandler)
+		http.HandleFunc("/entry/{id}/summaries", server.SummariesHandler)
+		http.HandleFunc("/entry/{id}/ask", server.AskHandler)
+		http.HandleFunc("/entry/{id}/ask/clear", server.ClearConversationHandler)
+		http.HandleFunc("/entry/{id}/{file}", server.ExportHandler)
+		http.HandleFunc("/subscriptions", server.SubscriptionsHandler)
+		http.HandleFunc("/subscriptions/{id}/remove", server.SubscriptionRemoveHandler)
+		http.HandleFunc("/subscriptions/{id}/check", server.SubscriptionCheckHandler)
 
 		staticFiles, err := fs.Sub(internalHttp.StaticFiles, "static")
 		if err != nil {
@@ -80,18 +297,75 @@ var runserverCmd = &cli.Command{
 		}
 		http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(staticFiles))))
 
-		worker, err := internalHttp.NewTranscriptionWorker(llmEndpoint, llmToken, llmModel, whisperModelPath, whisperLanguage, whisperQueueSize)
+		worker, err := internalHttp.NewTranscriptionWorker(internalHttp.TranscriptionWorkerConfig{
+			LLMEndpoint:      llmEndpoint,
+			LLMToken:         llmToken,
+			LLMModel:         llmModel,
+			LLMChunkTokens:   cmd.Int("llm-chunk-tokens"),
+			LLMChunkOverlap:  cmd.Int("llm-chunk-overlap"),
+			LLMStyles:        styles,
+			SummaryStyle:     cmd.String("summary-style"),
+			WhisperModelPath: whisperModelPath,
+			WhisperLanguage:  whisperLanguage,
+			WhisperQueueSize: whisperQueueSize,
+			YTDLPPath:        cmd.String("yt-dlp-path"),
+			FFMPEGPath:       cmd.String("ffmpeg-path"),
+			CaptionPolicy:    captionPolicy,
+			RecoveryPolicy:   recoveryPolicy,
+			Workers:          cmd.Int("workers"),
+			StageLimits: internalHttp.StageLimits{
+				Downloads:      cmd.Int("max-downloads"),
+				Transcriptions: cmd.Int("max-transcriptions"),
+				Summaries:      cmd.Int("max-summaries"),
+			},
+			StageTimeouts: proc.StageTimeouts{
+				Metadata:      cmd.Duration("metadata-timeout"),
+				Download:      cmd.Duration("download-timeout"),
+				Transcription: cmd.Duration("transcription-timeout"),
+				Summary:       cmd.Duration("summary-timeout"),
+			},
+		})
 		if err != nil {
 			return cli.Exit("Failed to initialize transcription worker: "+err.Error(), 1)
 		}
 
-		go worker.RunTranscriptionWorker(ctx) // Launch the background worker
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Launch the background worker
+		workerDone := make(chan struct{})
+		go func() {
+			worker.RunTranscriptionWorker(ctx)
+			close(workerDone)
+		}()
+
+		if subscriptionInterval > 0 {
+			go poller.Run(ctx)
+		}
 
 		port := cmd.Int("port")
+		httpServer := &http.Server{
+			Addr: fmt.Sprintf(":%d", port),
+			// End long-lived requests like entry event streams on shutdown instead of waiting for them
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+
+		go func() {
+			<-ctx.Done()
+			log.Println("Shutting down server...")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Error shutting down server: %v", err)
+			}
+		}()
 
 		log.Printf("Running server on http://localhost:%d", port)
-		log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return cli.Exit("Failed to run server: "+err.Error(), 1)
+		}
 
+		<-workerDone
 		return nil
 	},
 }
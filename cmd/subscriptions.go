@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/exler/yt-transcribe/internal/subscription"
+	"github.com/urfave/cli/v3"
+)
+
+var subscriptionsCmd = &cli.Command{
+	Name:  "subscriptions",
+	Usage: "Manage channels and playlists watched for new videos by runserver",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "data-dir",
+			Usage:    "Data directory of the server the subscriptions belong to",
+			Required: true,
+			Sources:  cli.EnvVars("DATA_DIR"),
+		},
+	},
+	Commands: []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List subscriptions",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				manager, err := openSubscriptions(cmd.String("data-dir"))
+				if err != nil {
+					return err
+				}
+
+				subscriptions, err := manager.List()
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Failed to load subscriptions: %v", err), 1)
+				}
+				if len(subscriptions) == 0 {
+					fmt.Println("No subscriptions")
+					return nil
+				}
+
+				for _, s := range subscriptions {
+					title := s.Title
+					if title == "" {
+						title = "(not checked yet)"
+					}
+					fmt.Printf("%s  %s  %s\n", s.ID, s.URL, title)
+					if s.LastError != "" {
+						fmt.Printf("    last error: %s\n", s.LastError)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "add",
+			Usage:     "Subscribe to a channel or playlist URL",
+			ArgsUsage: "URL",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				url := cmd.Args().First()
+				if url == "" {
+					return cli.Exit("Please provide a channel or playlist URL", 1)
+				}
+
+				manager, err := openSubscriptions(cmd.String("data-dir"))
+				if err != nil {
+					return err
+				}
+
+				created, err := manager.Add(url)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Failed to subscribe: %v", err), 1)
+				}
+				fmt.Printf("Subscribed to %s (ID %s)\n", created.URL, created.ID)
+				return nil
+			},
+		},
+		{
+			Name:      "remove",
+			Usage:     "Unsubscribe from a channel or playlist",
+			ArgsUsage: "ID",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				id := cmd.Args().First()
+				if id == "" {
+					return cli.Exit("Please provide a subscription ID", 1)
+				}
+
+				manager, err := openSubscriptions(cmd.String("data-dir"))
+				if err != nil {
+					return err
+				}
+
+				if err := manager.Remove(id); err != nil {
+					return cli.Exit(fmt.Sprintf("Failed to unsubscribe: %v", err), 1)
+				}
+				fmt.Printf("Unsubscribed %s\n", id)
+				return nil
+			},
+		},
+	},
+}
+
+func openSubscriptions(dataDir string) (*subscription.Manager, error) {
+	store, err := subscription.NewFileStore(dataDir)
+	if err != nil {
+		return nil, cli.Exit(fmt.Sprintf("Failed to open subscription storage: %v", err), 1)
+	}
+	return subscription.NewManager(store), nil
+}
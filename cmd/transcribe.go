@@ -1,20 +1,33 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/exler/yt-transcribe/internal/export"
 	"github.com/exler/yt-transcribe/internal/fetch"
 	"github.com/exler/yt-transcribe/internal/ffmpeg"
 	"github.com/exler/yt-transcribe/internal/llm"
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/transcript"
 	"github.com/urfave/cli/v3"
 )
 
 var (
 	transcribeCmd = &cli.Command{
-		Name:  "transcribe",
-		Usage: "Transcribe a YouTube video",
+		Name:      "transcribe",
+		Usage:     "Transcribe videos from YouTube or any other site supported by yt-dlp",
+		ArgsUsage: "[URL...] [-] (a trailing - reads URLs from stdin)",
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:  "summarize",
@@ -39,6 +52,30 @@ var (
 				Value:   "",
 				Sources: cli.EnvVars("LLM_MODEL"),
 			},
+			&cli.IntFlag{
+				Name:    "llm-chunk-tokens",
+				Usage:   "Approximate number of transcript tokens per LLM request, longer transcripts are summarized in parts",
+				Value:   llm.DefaultChunkTokens,
+				Sources: cli.EnvVars("LLM_CHUNK_TOKENS"),
+			},
+			&cli.IntFlag{
+				Name:    "llm-chunk-overlap",
+				Usage:   "Approximate number of tokens each part of a long transcript repeats from the previous one",
+				Value:   llm.DefaultChunkOverlap,
+				Sources: cli.EnvVars("LLM_CHUNK_OVERLAP"),
+			},
+			&cli.StringFlag{
+				Name:    "summary-style",
+				Usage:   "Prompt template to summarize with, e.g. 'default', 'tldr', 'bullets', 'minutes', 'study-notes' or a template from --prompt-dir",
+				Value:   llm.DefaultStyle,
+				Sources: cli.EnvVars("SUMMARY_STYLE"),
+			},
+			&cli.StringFlag{
+				Name:    "prompt-dir",
+				Usage:   "Directory with additional summary prompt templates (<style>.tmpl Go templates, replacing built-in styles of the same name)",
+				Value:   "",
+				Sources: cli.EnvVars("PROMPT_DIR"),
+			},
 			&cli.StringFlag{
 				Name:    "whisper-model-path",
 				Usage:   "Path to ggml whisper.cpp model file",
@@ -57,79 +94,561 @@ var (
 				Value:   15,
 				Sources: cli.EnvVars("WHISPER_QUEUE"),
 			},
+			&cli.StringFlag{
+				Name:    "yt-dlp-path",
+				Usage:   "Path to the yt-dlp binary",
+				Value:   "yt-dlp",
+				Sources: cli.EnvVars("YT_DLP_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "ffmpeg-path",
+				Usage:   "Path to the ffmpeg binary, which must be built with the whisper filter",
+				Value:   "ffmpeg",
+				Sources: cli.EnvVars("FFMPEG_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "captions",
+				Usage:   "Transcript source: 'whisper' to always transcribe, 'manual' to use uploaded captions when available or 'auto' to also accept automatic captions",
+				Value:   string(transcript.CaptionPolicyWhisper),
+				Sources: cli.EnvVars("CAPTIONS"),
+			},
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   "Output format: txt, srt, vtt, json or md (summaries support txt, json and md)",
+				Value:   string(export.FormatText),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "File to write the transcript (or summary with --summarize) to instead of stdout",
+			},
+			&cli.StringFlag{
+				Name:  "output-dir",
+				Usage: "Directory to write the transcript and summary to as sibling files, required for multiple videos",
+			},
+			&cli.StringFlag{
+				Name:  "filename-template",
+				Usage: "Go template for file names in --output-dir, with .VideoID, .Title, .UploadDate and .Duration",
+				Value: "{{.VideoID}}",
+			},
+			&cli.StringFlag{
+				Name:    "input-file",
+				Aliases: []string{"i"},
+				Usage:   "File with one URL per line to transcribe (use - for stdin)",
+			},
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "Transcribe a local audio or video file instead of URLs",
+			},
+			&cli.IntFlag{
+				Name:  "latest",
+				Usage: "Only transcribe the latest N videos of playlist and channel URLs (0 for all)",
+			},
+			&cli.StringFlag{
+				Name:  "uploaded-after",
+				Usage: "Only transcribe videos of playlist and channel URLs uploaded on or after this date (YYYYMMDD)",
+			},
+			&cli.IntFlag{
+				Name:  "parallel",
+				Usage: "Number of videos transcribed at the same time",
+				Value: 1,
+			},
+			&cli.DurationFlag{
+				Name:    "metadata-timeout",
+				Usage:   "Time limit for fetching the metadata of a video (0 for no limit)",
+				Value:   5 * time.Minute,
+				Sources: cli.EnvVars("METADATA_TIMEOUT"),
+			},
+			&cli.DurationFlag{
+				Name:    "download-timeout",
+				Usage:   "Time limit for downloading the captions or audio of a video (0 for no limit)",
+				Sources: cli.EnvVars("DOWNLOAD_TIMEOUT"),
+			},
+			&cli.DurationFlag{
+				Name:    "transcription-timeout",
+				Usage:   "Time limit for transcribing a video with the whisper filter (0 for no limit)",
+				Sources: cli.EnvVars("TRANSCRIPTION_TIMEOUT"),
+			},
+			&cli.DurationFlag{
+				Name:    "summary-timeout",
+				Usage:   "Time limit for summarizing a transcript with the LLM (0 for no limit)",
+				Sources: cli.EnvVars("SUMMARY_TIMEOUT"),
+			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			videoURL := cmd.Args().First()
-			if videoURL == "" {
-				return cli.Exit("Please provide a YouTube video URL to transcribe", 1)
+			// Cancel on Ctrl-C, so running yt-dlp and ffmpeg processes are killed with their children
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			localFile := cmd.String("file")
+			videoURLs, err := collectVideoURLs(cmd.Args().Slice(), cmd.String("input-file"))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if localFile != "" && len(videoURLs) > 0 {
+				return cli.Exit("--file cannot be combined with video URLs", 1)
+			}
+			if localFile == "" && len(videoURLs) == 0 {
+				return cli.Exit("Please provide a video URL or --file to transcribe", 1)
 			}
 
 			summarize := cmd.Bool("summarize")
 			llmEndpoint := cmd.String("llm-endpoint")
 			llmToken := cmd.String("llm-token")
 			llmModel := cmd.String("llm-model")
-			whisperModelPath := cmd.String("whisper-model-path")
-			whisperLanguage := cmd.String("whisper-language")
-			whisperQueueSize := cmd.Int("whisper-queue")
 
-			tempDir, err := os.MkdirTemp("", "yt-transcribe-*")
+			output, err := newOutputOptions(cmd.String("format"), cmd.String("output"), cmd.String("output-dir"), cmd.String("filename-template"))
 			if err != nil {
-				return cli.Exit(fmt.Sprintf("Failed to create temporary directory: %v", err), 1)
+				return cli.Exit(err.Error(), 1)
+			}
+			if err := output.validate(summarize); err != nil {
+				return cli.Exit(err.Error(), 1)
 			}
 
-			// Ensure cleanup of temp directory
-			defer func() {
-				if err := os.RemoveAll(tempDir); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup temporary files: %v\n", err)
+			// Local files don't need yt-dlp
+			if localFile == "" {
+				downloader, err := fetch.NewDownloader("")
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Failed to initialize downloader: %v", err), 1)
+				}
+				downloader.Binary = cmd.String("yt-dlp-path")
+				if err := downloader.CheckYTDLP(ctx); err != nil {
+					return cli.Exit(fmt.Sprintf("yt-dlp check failed: %v", err), 1)
 				}
-			}()
-
-			fmt.Printf("Using temporary directory: %s\n", tempDir)
-
-			downloader, err := fetch.NewYouTubeDownloader(tempDir)
-			if err != nil {
-				return cli.Exit(fmt.Sprintf("Failed to initialize YouTube downloader: %v", err), 1)
-			}
 
-			if err := downloader.CheckYTDLP(); err != nil {
-				return cli.Exit(fmt.Sprintf("yt-dlp check failed: %v", err), 1)
-			}
+				playlistOpts := fetch.PlaylistOptions{
+					Latest:        cmd.Int("latest"),
+					UploadedAfter: cmd.String("uploaded-after"),
+				}
+				if playlistOpts.UploadedAfter != "" {
+					if _, err := time.Parse("20060102", playlistOpts.UploadedAfter); err != nil {
+						return cli.Exit("--uploaded-after must be a date in the YYYYMMDD format", 1)
+					}
+				}
+				videoURLs = expandPlaylists(ctx, downloader, videoURLs, playlistOpts)
+				if len(videoURLs) == 0 {
+					return cli.Exit("No videos matched the playlist filters", 1)
+				}
 
-			fmt.Println("Downloading video...")
-			downloadedMetadata, err := downloader.DownloadAudio(videoURL)
-			if err != nil {
-				return cli.Exit(fmt.Sprintf("Failed to download audio: %v", err), 1)
+				// The results of several videos written to one stream could not be told apart
+				if len(videoURLs) > 1 && output.outputPath != "" {
+					return cli.Exit("--output cannot be used with multiple videos, use --output-dir instead", 1)
+				}
+				if len(videoURLs) > 1 && output.outputDir == "" {
+					return cli.Exit(fmt.Sprintf("%d videos matched, use --output-dir to write each to its own files", len(videoURLs)), 1)
+				}
 			}
 
 			ff, err := ffmpeg.NewFFMPEG()
 			if err != nil {
 				return cli.Exit(fmt.Sprintf("Failed to initialize ffmpeg: %v", err), 1)
 			}
+			ff.Binary = cmd.String("ffmpeg-path")
 
-			fmt.Println("Transcribing audio with FFmpeg whisper filter...")
-			transcriptionText, err := ff.TranscribeWithWhisperFilter(downloadedMetadata.AudioFilePath, whisperModelPath, whisperLanguage, whisperQueueSize)
-			if err != nil {
-				return cli.Exit(fmt.Sprintf("Failed to transcribe audio with whisper filter: %v", err), 1)
-			}
-
+			var summarizer llm.Summarizer
+			summaryStyle := cmd.String("summary-style")
 			if summarize {
-				summarizer, err := llm.NewSummarizer(llmEndpoint, llmToken, llmModel)
+				styles, err := llm.LoadStyles(cmd.String("prompt-dir"))
 				if err != nil {
-					return cli.Exit(fmt.Sprintf("Failed to initialize summarizer: %v", err), 1)
+					return cli.Exit(err.Error(), 1)
+				}
+				if err := styles.Validate(summaryStyle); err != nil {
+					return cli.Exit(err.Error(), 1)
 				}
 
-				summary, err := summarizer.SummarizeText(ctx, downloadedMetadata.Title, transcriptionText)
+				summarizer, err = llm.NewSummarizer(llm.Config{
+					Endpoint:     llmEndpoint,
+					Token:        llmToken,
+					Model:        llmModel,
+					ChunkTokens:  cmd.Int("llm-chunk-tokens"),
+					ChunkOverlap: cmd.Int("llm-chunk-overlap"),
+					Styles:       styles,
+				})
 				if err != nil {
-					return cli.Exit(fmt.Sprintf("Failed to summarize transcription: %v", err), 1)
+					return cli.Exit(fmt.Sprintf("Failed to initialize summarizer: %v", err), 1)
 				}
+			}
 
-				fmt.Println("Summary:")
-				fmt.Println(summary)
-			} else {
-				fmt.Println(transcriptionText)
+			captionPolicy, err := transcript.ParseCaptionPolicy(cmd.String("captions"))
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			opts := &transcribeOptions{
+				ffmpeg:           ff,
+				ytdlpPath:        cmd.String("yt-dlp-path"),
+				summarizer:       summarizer,
+				summaryStyle:     summaryStyle,
+				whisperModelPath: cmd.String("whisper-model-path"),
+				whisperLanguage:  cmd.String("whisper-language"),
+				whisperQueueSize: cmd.Int("whisper-queue"),
+				captionPolicy:    captionPolicy,
+				timeouts: proc.StageTimeouts{
+					Metadata:      cmd.Duration("metadata-timeout"),
+					Download:      cmd.Duration("download-timeout"),
+					Transcription: cmd.Duration("transcription-timeout"),
+					Summary:       cmd.Duration("summary-timeout"),
+				},
+				output: output,
+			}
+
+			// A single video keeps the plain progress output and error messages
+			logf := func(format string, args ...any) {
+				fmt.Fprintf(os.Stderr, format+"\n", args...)
+			}
+			opts.showProgress = localFile != "" || len(videoURLs) == 1
+			if localFile != "" {
+				if err := transcribeFile(ctx, localFile, opts, logf); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				return nil
+			}
+			if len(videoURLs) == 1 {
+				if _, err := transcribeURL(ctx, videoURLs[0], opts, logf); err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				return nil
 			}
 
+			results := transcribeBatch(ctx, videoURLs, cmd.Int("parallel"), opts)
+
+			failed := printBatchReport(results)
+			if failed > 0 {
+				return cli.Exit(fmt.Sprintf("%d of %d videos failed", failed, len(results)), 1)
+			}
 			return nil
 		},
 	}
 )
+
+// transcribeOptions holds the settings shared by all videos transcribed in one run.
+type transcribeOptions struct {
+	ffmpeg           *ffmpeg.FFMPEG
+	ytdlpPath        string
+	summarizer       llm.Summarizer // nil when not summarizing
+	summaryStyle     string
+	whisperModelPath string
+	whisperLanguage  string
+	whisperQueueSize int
+	captionPolicy    transcript.CaptionPolicy
+	showProgress     bool // Draw a transcription progress bar, only used when transcribing a single video
+	timeouts         proc.StageTimeouts
+
+	output outputOptions
+	// Serializes writes, so results of parallel transcriptions are not interleaved.
+	outputMu sync.Mutex
+}
+
+// batchResult is the outcome of transcribing a single video of a batch.
+type batchResult struct {
+	videoURL string
+	title    string
+	err      error
+}
+
+// collectVideoURLs gathers URLs from the arguments and the input file.
+// "-" as an argument or input file reads URLs from stdin.
+func collectVideoURLs(args []string, inputFile string) ([]string, error) {
+	var videoURLs []string
+	readStdin := inputFile == "-"
+
+	for _, arg := range args {
+		if arg == "-" {
+			readStdin = true
+			continue
+		}
+		videoURLs = append(videoURLs, arg)
+	}
+
+	if inputFile != "" && inputFile != "-" {
+		f, err := os.Open(inputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer f.Close()
+
+		fileURLs, err := readVideoURLs(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+		videoURLs = append(videoURLs, fileURLs...)
+	}
+
+	if readStdin {
+		stdinURLs, err := readVideoURLs(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read URLs from stdin: %w", err)
+		}
+		videoURLs = append(videoURLs, stdinURLs...)
+	}
+
+	return videoURLs, nil
+}
+
+// readVideoURLs reads one URL per line, skipping blank lines and # comments.
+func readVideoURLs(r io.Reader) ([]string, error) {
+	var videoURLs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		videoURLs = append(videoURLs, line)
+	}
+	return videoURLs, scanner.Err()
+}
+
+// expandPlaylists replaces playlist and channel URLs with the URLs of their videos.
+// URLs that cannot be listed are kept as they are, so they are reported by the transcription.
+func expandPlaylists(ctx context.Context, downloader *fetch.Downloader, videoURLs []string, opts fetch.PlaylistOptions) []string {
+	var expanded []string
+	for _, videoURL := range videoURLs {
+		playlist, err := downloader.ListPlaylist(ctx, videoURL, opts)
+		if err != nil || !playlist.IsPlaylist {
+			expanded = append(expanded, videoURL)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Found %d videos in %s\n", len(playlist.Entries), playlist.Title)
+		for _, entry := range playlist.Entries {
+			expanded = append(expanded, entry.URL)
+		}
+	}
+	return expanded
+}
+
+// transcribeBatch transcribes the videos with up to parallel videos at the same time.
+// Results are returned in the order of the URLs.
+func transcribeBatch(ctx context.Context, videoURLs []string, parallel int, opts *transcribeOptions) []batchResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]batchResult, len(videoURLs))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, videoURL := range videoURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			// Prefix progress messages with the position of the video in the batch
+			logf := func(format string, args ...any) {
+				fmt.Fprintf(os.Stderr, "[%d/%d] "+format+"\n", append([]any{i + 1, len(videoURLs)}, args...)...)
+			}
+
+			result := batchResult{videoURL: videoURL}
+			if ctx.Err() != nil {
+				result.err = ctx.Err()
+			} else {
+				result.title, result.err = transcribeURL(ctx, videoURL, opts, logf)
+			}
+			if result.err != nil {
+				logf("Failed: %v", result.err)
+			}
+			results[i] = result
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// printBatchReport prints the outcome of each video to stderr and returns the number of failures.
+func printBatchReport(results []batchResult) int {
+	failed := 0
+	fmt.Fprintln(os.Stderr, "\nResults:")
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "  FAILED  %s: %v\n", result.videoURL, result.err)
+		} else {
+			fmt.Fprintf(os.Stderr, "  OK      %s (%s)\n", result.videoURL, result.title)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Transcribed %d of %d videos, %d failed\n", len(results)-failed, len(results), failed)
+	return failed
+}
+
+// transcribeURL downloads, transcribes and optionally summarizes a single video and writes the result.
+// Progress messages are reported through logf. It returns the title of the video.
+func transcribeURL(ctx context.Context, videoURL string, opts *transcribeOptions, logf func(format string, args ...any)) (string, error) {
+	tempDir, err := os.MkdirTemp("", "yt-transcribe-*")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create temporary directory: %w", err)
+	}
+
+	// Ensure cleanup of temp directory
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup temporary files: %v\n", err)
+		}
+	}()
+
+	logf("Using temporary directory: %s", tempDir)
+
+	downloader, err := fetch.NewDownloader(tempDir)
+	if err != nil {
+		return "", fmt.Errorf("Failed to initialize downloader: %w", err)
+	}
+	downloader.Binary = opts.ytdlpPath
+
+	downloadedMetadata, videoTranscript, err := fetchTranscript(ctx, downloader, videoURL, opts, logf)
+	if err != nil {
+		return downloadedMetadata.Title, err
+	}
+
+	return downloadedMetadata.Title, finishTranscript(ctx, downloadedMetadata, videoTranscript, opts, logf)
+}
+
+// transcribeFile transcribes a local audio or video file without going through yt-dlp.
+func transcribeFile(ctx context.Context, path string, opts *transcribeOptions, logf func(format string, args ...any)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("Failed to open file: %s is a directory", path)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	metadata := fetch.VideoMetadata{
+		Extractor:     "file",
+		VideoID:       name,
+		Title:         name,
+		AudioFilePath: path,
+	}
+
+	logf("Transcribing %s with FFmpeg whisper filter...", path)
+	videoTranscript, err := transcribeAudio(ctx, path, opts)
+	if err != nil {
+		return fmt.Errorf("Failed to transcribe audio with whisper filter: %w", err)
+	}
+
+	return finishTranscript(ctx, metadata, videoTranscript, opts, logf)
+}
+
+// transcribeAudio transcribes an audio or video file with the whisper filter,
+// drawing a progress bar to stderr if it is a terminal and opts.showProgress is set.
+func transcribeAudio(ctx context.Context, path string, opts *transcribeOptions) (transcript.Transcript, error) {
+	ff := *opts.ffmpeg
+	if opts.showProgress {
+		if bar := newProgressBar(os.Stderr); bar != nil {
+			ff.OnProgress = bar.Update
+			defer bar.Finish()
+		}
+	}
+
+	transcribeCtx, cancel := proc.WithTimeout(ctx, opts.timeouts.Transcription)
+	defer cancel()
+	videoTranscript, err := ff.TranscribeWithWhisperFilter(transcribeCtx, path, opts.whisperModelPath, opts.whisperLanguage, opts.whisperQueueSize)
+	return videoTranscript, proc.TimeoutError(transcribeCtx, opts.timeouts.Transcription, err)
+}
+
+// finishTranscript summarizes the transcript if requested and writes the output.
+func finishTranscript(ctx context.Context, metadata fetch.VideoMetadata, videoTranscript transcript.Transcript, opts *transcribeOptions, logf func(format string, args ...any)) error {
+	var summary llm.Summary
+	streamed := false
+	if opts.summarizer != nil {
+		logf("Summarizing transcription (style: %s)...", opts.summaryStyle)
+
+		// Print the summary of a single video as it is written: to stdout if it is the output anyway,
+		// otherwise as a preview on the terminal
+		var summaryOut *os.File
+		if opts.showProgress {
+			if opts.output.isPlainStdout() {
+				summaryOut, streamed = os.Stdout, true
+			} else if isTerminal(os.Stderr) {
+				summaryOut = os.Stderr
+			}
+		}
+		summaryOpts := llm.SummaryOptions{Style: opts.summaryStyle}
+		if summaryOut != nil {
+			summaryOpts.OnText = func(text string) { summaryOut.WriteString(text) }
+		}
+
+		var err error
+		summaryCtx, cancel := proc.WithTimeout(ctx, opts.timeouts.Summary)
+		summary, err = opts.summarizer.SummarizeTranscript(summaryCtx, llm.Video{
+			Title:       metadata.Title,
+			Channel:     metadata.Channel,
+			Description: metadata.Description,
+			Duration:    metadata.Duration,
+			UploadDate:  metadata.UploadDate,
+			URL:         metadata.OriginalURL,
+			Tags:        metadata.Tags,
+			Chapters:    metadata.Chapters,
+		}, videoTranscript, summaryOpts)
+		err = proc.TimeoutError(summaryCtx, opts.timeouts.Summary, err)
+		cancel()
+		if summaryOut != nil {
+			fmt.Fprintln(summaryOut)
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to summarize transcription: %w", err)
+		}
+	}
+	if streamed {
+		return nil
+	}
+
+	opts.outputMu.Lock()
+	defer opts.outputMu.Unlock()
+	if err := opts.output.write(metadata, videoTranscript, summary.Text, opts.summarizer != nil); err != nil {
+		return fmt.Errorf("Failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+// fetchTranscript uses the existing captions of a video if the caption policy allows it,
+// otherwise it downloads the audio and transcribes it with the whisper filter.
+func fetchTranscript(ctx context.Context, downloader *fetch.Downloader, videoURL string, opts *transcribeOptions, logf func(format string, args ...any)) (fetch.VideoMetadata, transcript.Transcript, error) {
+	if opts.captionPolicy != transcript.CaptionPolicyWhisper {
+		// The metadata tells which of the caption tracks is in the language of the video
+		metadataCtx, cancel := proc.WithTimeout(ctx, opts.timeouts.Metadata)
+		metadata, err := downloader.GetVideoMetadata(metadataCtx, videoURL)
+		err = proc.TimeoutError(metadataCtx, opts.timeouts.Metadata, err)
+		cancel()
+		if err != nil {
+			return metadata, transcript.Transcript{}, fmt.Errorf("Failed to fetch video metadata: %w", err)
+		}
+
+		logf("Fetching captions for %s...", videoURL)
+		captionsCtx, cancel := proc.WithTimeout(ctx, opts.timeouts.Download)
+		captions, err := downloader.FetchCaptions(captionsCtx, videoURL, opts.whisperLanguage, metadata.Language, opts.captionPolicy)
+		err = proc.TimeoutError(captionsCtx, opts.timeouts.Download, err)
+		cancel()
+		switch {
+		case err == nil:
+			logf("Using existing %s", strings.ToLower(captions.Source.Label()))
+			return metadata, captions, nil
+		case errors.Is(err, fetch.ErrNoSubtitles):
+			logf("No usable captions, falling back to Whisper")
+		default:
+			logf("Failed to fetch captions, falling back to Whisper: %v", err)
+		}
+	}
+
+	logf("Downloading video %s...", videoURL)
+	downloadCtx, cancel := proc.WithTimeout(ctx, opts.timeouts.Download)
+	downloadedMetadata, err := downloader.DownloadAudio(downloadCtx, videoURL)
+	err = proc.TimeoutError(downloadCtx, opts.timeouts.Download, err)
+	cancel()
+	if err != nil {
+		return downloadedMetadata, transcript.Transcript{}, fmt.Errorf("Failed to download audio: %w", err)
+	}
+
+	logf("Transcribing audio with FFmpeg whisper filter...")
+	videoTranscript, err := transcribeAudio(ctx, downloadedMetadata.AudioFilePath, opts)
+	if err != nil {
+		return downloadedMetadata, transcript.Transcript{}, fmt.Errorf("Failed to transcribe audio with whisper filter: %w", err)
+	}
+	return downloadedMetadata, videoTranscript, nil
+}
@@ -21,6 +21,18 @@ var (
 				Aliases: []string{"v"},
 				Usage:   "Show versions of ffmpeg and yt-dlp dependencies",
 			},
+			&cli.StringFlag{
+				Name:    "yt-dlp-path",
+				Usage:   "Path to the yt-dlp binary",
+				Value:   "yt-dlp",
+				Sources: cli.EnvVars("YT_DLP_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "ffmpeg-path",
+				Usage:   "Path to the ffmpeg binary, which must be built with the whisper filter",
+				Value:   "ffmpeg",
+				Sources: cli.EnvVars("FFMPEG_PATH"),
+			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			fmt.Printf("yt-transcribe %s\n", Version)
@@ -29,19 +41,25 @@ var (
 				ffmpegProcessor, err := ffmpeg.NewFFMPEG()
 				if err != nil {
 					fmt.Printf("ffmpeg initialization error: %v\n", err)
-				} else if ffmpegVersion, err := ffmpegProcessor.GetFFMPEGVersion(); err == nil {
-					fmt.Printf("ffmpeg %s\n", ffmpegVersion)
 				} else {
-					fmt.Printf("ffmpeg not found or error: %v\n", err)
+					ffmpegProcessor.Binary = cmd.String("ffmpeg-path")
+					if ffmpegVersion, err := ffmpegProcessor.GetFFMPEGVersion(ctx); err == nil {
+						fmt.Printf("ffmpeg %s\n", ffmpegVersion)
+					} else {
+						fmt.Printf("ffmpeg not found or error: %v\n", err)
+					}
 				}
 
-				downloader, err := fetch.NewYouTubeDownloader("")
+				downloader, err := fetch.NewDownloader("")
 				if err != nil {
 					fmt.Printf("yt-dlp initialization error: %v\n", err)
-				} else if ytDlpVersion, err := downloader.GetYTDLPVersion(); err == nil {
-					fmt.Printf("yt-dlp %s\n", ytDlpVersion)
 				} else {
-					fmt.Printf("yt-dlp not found or error: %v\n", err)
+					downloader.Binary = cmd.String("yt-dlp-path")
+					if ytDlpVersion, err := downloader.GetYTDLPVersion(ctx); err == nil {
+						fmt.Printf("yt-dlp %s\n", ytDlpVersion)
+					} else {
+						fmt.Printf("yt-dlp not found or error: %v\n", err)
+					}
 				}
 			}
 
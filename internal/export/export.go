@@ -0,0 +1,154 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// Format is a file format transcripts and summaries can be exported to.
+type Format string
+
+const (
+	FormatText     Format = "txt"
+	FormatSRT      Format = "srt"
+	FormatVTT      Format = "vtt"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "md"
+)
+
+// TranscriptFormats lists the formats a transcript can be exported to.
+var TranscriptFormats = []Format{FormatText, FormatSRT, FormatVTT, FormatJSON, FormatMarkdown}
+
+// SummaryFormats lists the formats a summary can be exported to.
+var SummaryFormats = []Format{FormatText, FormatMarkdown, FormatJSON}
+
+// ParseFormat validates a format name, e.g. "srt".
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	for _, f := range TranscriptFormats {
+		if f == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatSRT:
+		return "application/x-subrip; charset=utf-8"
+	case FormatVTT:
+		return "text/vtt; charset=utf-8"
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// jsonSegment is a transcript segment with times in seconds, as written to JSON exports.
+type jsonSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcript renders a transcript in the given format.
+func Transcript(t transcript.Transcript, title string, format Format) ([]byte, error) {
+	switch format {
+	case FormatText:
+		return []byte(t.Text() + "\n"), nil
+	case FormatSRT:
+		return []byte(t.SRT()), nil
+	case FormatVTT:
+		return []byte(t.VTT()), nil
+	case FormatJSON:
+		segments := make([]jsonSegment, 0, len(t.Segments))
+		for _, segment := range t.Segments {
+			segments = append(segments, jsonSegment{
+				Start: segment.Start.Seconds(),
+				End:   segment.End.Seconds(),
+				Text:  segment.Text,
+			})
+		}
+		return marshalJSON(struct {
+			Title    string            `json:"title"`
+			Source   transcript.Source `json:"source,omitempty"`
+			Segments []jsonSegment     `json:"segments"`
+		}{title, t.Source, segments})
+	case FormatMarkdown:
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s\n\n## Transcript\n\n", title)
+		for _, segment := range t.Segments {
+			fmt.Fprintf(&b, "**[%s]** %s\n\n", transcript.FormatClock(segment.Start), segment.Text)
+		}
+		return []byte(b.String()), nil
+	default:
+		return nil, fmt.Errorf("transcripts cannot be exported as %q", format)
+	}
+}
+
+// Summary renders a summary in the given format.
+func Summary(summary string, title string, format Format) ([]byte, error) {
+	switch format {
+	case FormatText:
+		return []byte(summary + "\n"), nil
+	case FormatJSON:
+		return marshalJSON(struct {
+			Title   string `json:"title"`
+			Summary string `json:"summary"`
+		}{title, summary})
+	case FormatMarkdown:
+		return []byte(fmt.Sprintf("# %s\n\n## Summary\n\n%s\n", title, summary)), nil
+	default:
+		return nil, fmt.Errorf("summaries cannot be exported as %q", format)
+	}
+}
+
+// Filename builds a download filename like "Video title - transcript.srt".
+func Filename(title string, kind string, format Format) string {
+	return fmt.Sprintf("%s - %s.%s", SanitizeFilename(title), kind, format)
+}
+
+// SanitizeFilename replaces characters that are not allowed in filenames on common systems.
+// Names left empty, "." or "..", which would refer to a directory, are replaced by "video".
+func SanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	if name = strings.TrimSpace(name); name == "" || name == "." || name == ".." {
+		name = "video"
+	}
+	return name
+}
+
+// SupportsSummary reports whether summaries can be exported in the format.
+func (f Format) SupportsSummary() bool {
+	for _, format := range SummaryFormats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+func marshalJSON(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+var testTranscript = transcript.Transcript{
+	Segments: []transcript.Segment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "Hello"},
+		{Start: 1500 * time.Millisecond, End: 62 * time.Second, Text: "world"},
+	},
+	Source: transcript.SourceWhisper,
+}
+
+func TestTranscript(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatText, "Hello\nworld\n"},
+		{FormatSRT, "1\n00:00:00,000 --> 00:00:01,500\nHello\n\n2\n00:00:01,500 --> 00:01:02,000\nworld\n\n"},
+		{FormatVTT, "WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nHello\n\n00:00:01.500 --> 00:01:02.000\nworld\n\n"},
+		{FormatJSON, `{
+  "title": "Talk",
+  "source": "whisper",
+  "segments": [
+    {
+      "start": 0,
+      "end": 1.5,
+      "text": "Hello"
+    },
+    {
+      "start": 1.5,
+      "end": 62,
+      "text": "world"
+    }
+  ]
+}
+`},
+		{FormatMarkdown, "# Talk\n\n## Transcript\n\n**[0:00]** Hello\n\n**[0:01]** world\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := Transcript(testTranscript, "Talk", tt.format)
+			if err != nil {
+				t.Fatalf("Transcript: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Transcript = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Transcript(testTranscript, "Talk", "pdf"); err == nil {
+		t.Error("Transcript accepted an unknown format")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatText, "Short summary\n"},
+		{FormatJSON, "{\n  \"title\": \"Talk\",\n  \"summary\": \"Short summary\"\n}\n"},
+		{FormatMarkdown, "# Talk\n\n## Summary\n\nShort summary\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			if !tt.format.SupportsSummary() {
+				t.Errorf("%s does not support summaries", tt.format)
+			}
+			got, err := Summary("Short summary", "Talk", tt.format)
+			if err != nil {
+				t.Fatalf("Summary: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Summary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, format := range []Format{FormatSRT, FormatVTT} {
+		if format.SupportsSummary() {
+			t.Errorf("%s supports summaries", format)
+		}
+		if _, err := Summary("Short summary", "Talk", format); err == nil {
+			t.Errorf("Summary accepted %s", format)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("SRT"); err != nil || format != FormatSRT {
+		t.Errorf("ParseFormat(SRT) = %q, %v", format, err)
+	}
+	if _, err := ParseFormat("docx"); err == nil {
+		t.Error("ParseFormat accepted an unknown format")
+	}
+}
+
+func TestFilename(t *testing.T) {
+	tests := []struct {
+		title string
+		kind  string
+		want  string
+	}{
+		{"Talk", "transcript", "Talk - transcript.srt"},
+		{"AC/DC: Live?", "summary", "AC_DC_ Live_ - summary.srt"},
+		{"..", "transcript", "video - transcript.srt"},
+		{"", "transcript", "video - transcript.srt"},
+	}
+	for _, tt := range tests {
+		if got := Filename(tt.title, tt.kind, FormatSRT); got != tt.want {
+			t.Errorf("Filename(%q, %q) = %q, want %q", tt.title, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Video title", "Video title"},
+		{`a/b\c:d*e?f"g<h>i|j`, "a_b_c_d_e_f_g_h_i_j"},
+		{"  padded\t", "padded"},
+		{"line\nbreak", "linebreak"},
+		{"...", "..."},
+		{".hidden", ".hidden"},
+		{"", "video"},
+		{"   ", "video"},
+		{"\x00\x01", "video"},
+		{".", "video"},
+		{"..", "video"},
+		{" .. ", "video"},
+		{"\x01..", "video"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.name); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
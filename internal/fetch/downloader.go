@@ -0,0 +1,272 @@
+package fetch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// VideoMetadata holds metadata for a downloaded video
+type VideoMetadata struct {
+	Extractor     string // yt-dlp extractor of the video, e.g. "youtube" or "vimeo"
+	Title         string
+	Duration      string // e.g., "10:35"
+	UploadDate    string // e.g., "20231026"
+	AudioFilePath string
+	VideoID       string
+	Channel       string
+	ChannelURL    string
+	Description   string
+	Tags          []string
+	Chapters      []transcript.Chapter
+	ThumbnailURL  string
+	ViewCount     int64
+	Language      string // Empty if unknown
+	OriginalURL   string
+}
+
+// videoInfo is the subset of yt-dlp's JSON output used for video metadata.
+type videoInfo struct {
+	ID             string   `json:"id"`
+	ExtractorKey   string   `json:"extractor_key"`
+	Title          string   `json:"title"`
+	Duration       float64  `json:"duration"`
+	DurationString string   `json:"duration_string"`
+	UploadDate     string   `json:"upload_date"`
+	Channel        string   `json:"channel"`
+	ChannelURL     string   `json:"channel_url"`
+	Uploader       string   `json:"uploader"`
+	UploaderURL    string   `json:"uploader_url"`
+	Description    string   `json:"description"`
+	Tags           []string `json:"tags"`
+	Chapters       []struct {
+		Title     string  `json:"title"`
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+	} `json:"chapters"`
+	Thumbnail   string `json:"thumbnail"`
+	ViewCount   int64  `json:"view_count"`
+	Language    string `json:"language"`
+	OriginalURL string `json:"original_url"`
+	WebpageURL  string `json:"webpage_url"`
+}
+
+// parseVideoMetadata parses a single line of yt-dlp's JSON video info.
+func parseVideoMetadata(data []byte) (VideoMetadata, error) {
+	var info videoInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return VideoMetadata{}, fmt.Errorf("failed to parse yt-dlp metadata: %w", err)
+	}
+	if info.ID == "" {
+		return VideoMetadata{}, fmt.Errorf("yt-dlp metadata does not contain a video ID")
+	}
+
+	metadata := VideoMetadata{
+		Extractor:    normalizeExtractor(info.ExtractorKey),
+		VideoID:      info.ID,
+		Title:        info.Title,
+		UploadDate:   info.UploadDate,
+		Channel:      cmp.Or(info.Channel, info.Uploader),
+		ChannelURL:   cmp.Or(info.ChannelURL, info.UploaderURL),
+		Description:  info.Description,
+		Tags:         info.Tags,
+		ThumbnailURL: info.Thumbnail,
+		ViewCount:    info.ViewCount,
+		Language:     info.Language,
+		OriginalURL:  cmp.Or(info.OriginalURL, info.WebpageURL),
+	}
+	if info.DurationString != "" {
+		metadata.Duration = formatDuration(info.DurationString)
+	} else if info.Duration > 0 {
+		metadata.Duration = formatSeconds(int(info.Duration))
+	}
+	for _, c := range info.Chapters {
+		metadata.Chapters = append(metadata.Chapters, transcript.Chapter{
+			Title: c.Title,
+			Start: secondsToDuration(c.StartTime),
+			End:   secondsToDuration(c.EndTime),
+		})
+	}
+	return metadata, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// normalizeExtractor turns a yt-dlp extractor key like "Youtube" or "TwitchVod" into a lowercase name.
+func normalizeExtractor(key string) string {
+	return strings.ToLower(key)
+}
+
+// defaultBinary is the yt-dlp binary used when Downloader.Binary is empty, looked up on PATH.
+const defaultBinary = "yt-dlp"
+
+// Downloader downloads videos from YouTube or any other site supported by yt-dlp
+type Downloader struct {
+	OutputDir string
+	// Path of the yt-dlp binary, empty to look up yt-dlp on PATH
+	Binary string
+	// Runs yt-dlp, nil to run it as a process
+	Runner proc.Runner
+	// Optional, called with progress updates while DownloadAudio downloads a video
+	OnProgress func(DownloadProgress)
+}
+
+// NewDownloader creates a new video downloader instance
+func NewDownloader(outputDir string) (*Downloader, error) {
+	// Get absolute path for output directory
+	absPath, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	return &Downloader{
+		OutputDir: absPath,
+	}, nil
+}
+
+// run runs yt-dlp with the given arguments.
+func (d *Downloader) run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	runner := d.Runner
+	if runner == nil {
+		runner = proc.ExecRunner{}
+	}
+	return runner.Run(ctx, cmp.Or(d.Binary, defaultBinary), args, stdout, stderr)
+}
+
+// CheckYTDLP verifies that yt-dlp is installed
+func (d *Downloader) CheckYTDLP(ctx context.Context) error {
+	if err := d.run(ctx, []string{"--version"}, io.Discard, io.Discard); err != nil {
+		return fmt.Errorf("yt-dlp not found: %w", err)
+	}
+	return nil
+}
+
+// GetYTDLPVersion retrieves the version of the yt-dlp package
+func (d *Downloader) GetYTDLPVersion(ctx context.Context) (string, error) {
+	var output bytes.Buffer
+	if err := d.run(ctx, []string{"--version"}, &output, io.Discard); err != nil {
+		return "", fmt.Errorf("yt-dlp not found: %w", err)
+	}
+
+	// yt-dlp --version returns just the version string
+	version := strings.TrimSpace(output.String())
+	return version, nil
+}
+
+func formatDuration(duration string) string {
+	// If duration is less than a minute, format it as "0:<secs>"
+	if strings.Count(duration, ":") == 0 {
+		return fmt.Sprintf("0:%s", duration)
+	}
+
+	return duration
+}
+
+// DownloadAudio downloads a video (converted to audio format) using yt-dlp
+// and returns its metadata. The download is aborted when the context is cancelled.
+func (d *Downloader) DownloadAudio(ctx context.Context, videoURL string, options ...string) (VideoMetadata, error) {
+	metadata := VideoMetadata{}
+
+	if err := d.CheckYTDLP(ctx); err != nil {
+		return metadata, err
+	}
+
+	// Create a unique filename based on the video ID
+	outputTemplate := filepath.Join(d.OutputDir, "%(id)s.%(ext)s")
+
+	args := []string{
+		"--format", "bestaudio/best",
+		"--extract-audio",
+		"--audio-format", "m4a",
+		"--output", outputTemplate,
+		"--no-playlist",
+		"--no-simulate",
+		"--quiet",
+		"--print", "%()j",
+		"--print", "after_move:filepath",
+	}
+	if d.OnProgress != nil {
+		args = append(args, "--progress", "--newline", "--progress-template", progressTemplate)
+	}
+	args = append(args, options...)
+	args = append(args, videoURL)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	var stdoutWriter, stderrWriter io.Writer = &stdout, &stderr
+
+	// Progress is printed to stderr in quiet mode, but check both streams to be safe
+	var progressOut, progressErr *progressWriter
+	if d.OnProgress != nil {
+		var mu sync.Mutex
+		progressOut = &progressWriter{out: &stdout, onProgress: d.OnProgress, mu: &mu}
+		progressErr = &progressWriter{out: &stderr, onProgress: d.OnProgress, mu: &mu}
+		stdoutWriter, stderrWriter = progressOut, progressErr
+	}
+
+	err := d.run(ctx, args, stdoutWriter, stderrWriter)
+	if progressOut != nil {
+		progressOut.Flush()
+		progressErr.Flush()
+	}
+	if err != nil {
+		return metadata, fmt.Errorf("failed to download video: %w\nStderr: %s", err, stderr.String())
+	}
+
+	outputLines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(outputLines) < 2 {
+		return metadata, fmt.Errorf("yt-dlp output did not contain enough lines for metadata and filename. Output: %s", stdout.String())
+	}
+
+	// The first line is the video info and the second is the final file path.
+	// As multiple --print args are used, they are printed in the order they appear in the command.
+	metadata, err = parseVideoMetadata([]byte(outputLines[0]))
+	if err != nil {
+		return metadata, err
+	}
+	metadata.AudioFilePath = outputLines[len(outputLines)-1]
+
+	return metadata, nil
+}
+
+// GetVideoMetadata fetches metadata for a video without downloading the video.
+func (d *Downloader) GetVideoMetadata(ctx context.Context, videoURL string) (VideoMetadata, error) {
+	metadata := VideoMetadata{}
+
+	if err := d.CheckYTDLP(ctx); err != nil {
+		return metadata, err
+	}
+
+	args := []string{
+		"--dump-json",
+		"--no-playlist",
+		"--quiet",
+		"--no-warnings",
+		videoURL,
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if err := d.run(ctx, args, &stdout, &stderr); err != nil {
+		return metadata, fmt.Errorf("failed to fetch video metadata: %w\nStderr: %s", err, stderr.String())
+	}
+
+	outputStr := strings.TrimSpace(stdout.String())
+	if outputStr == "" {
+		return metadata, fmt.Errorf("yt-dlp did not return any metadata output")
+	}
+
+	return parseVideoMetadata([]byte(outputStr))
+}
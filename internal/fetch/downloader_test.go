@@ -0,0 +1,246 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+const videoJSON = `{"id":"abc123","extractor_key":"Youtube","title":"A video","duration":635,"duration_string":"10:35",` +
+	`"upload_date":"20231026","uploader":"Uploader","uploader_url":"https://example.com/u","description":"Desc",` +
+	`"tags":["go","test"],"chapters":[{"title":"Intro","start_time":0,"end_time":12.5},{"title":"Main","start_time":12.5,"end_time":635}],` +
+	`"thumbnail":"https://example.com/t.jpg","view_count":42,"language":"en","webpage_url":"https://www.youtube.com/watch?v=abc123"}`
+
+// fakeYTDLP returns a downloader running a fake yt-dlp. It answers --version itself and passes
+// every other call to handle.
+func fakeYTDLP(t *testing.T, handle func(args []string, stdout, stderr io.Writer) error) *Downloader {
+	t.Helper()
+	return &Downloader{
+		OutputDir: t.TempDir(),
+		Binary:    "/opt/yt-dlp",
+		Runner: proc.RunnerFunc(func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+			if name != "/opt/yt-dlp" {
+				t.Errorf("ran %q, want the configured binary", name)
+			}
+			if slices.Equal(args, []string{"--version"}) {
+				io.WriteString(stdout, "2025.01.01\n")
+				return nil
+			}
+			return handle(args, stdout, stderr)
+		}),
+	}
+}
+
+func TestParseVideoMetadata(t *testing.T) {
+	metadata, err := parseVideoMetadata([]byte(videoJSON))
+	if err != nil {
+		t.Fatalf("parseVideoMetadata: %v", err)
+	}
+
+	want := VideoMetadata{
+		Extractor:    "youtube",
+		VideoID:      "abc123",
+		Title:        "A video",
+		Duration:     "10:35",
+		UploadDate:   "20231026",
+		Channel:      "Uploader",
+		ChannelURL:   "https://example.com/u",
+		Description:  "Desc",
+		ThumbnailURL: "https://example.com/t.jpg",
+		ViewCount:    42,
+		Language:     "en",
+		OriginalURL:  "https://www.youtube.com/watch?v=abc123",
+		Tags:         []string{"go", "test"},
+		Chapters: []transcript.Chapter{
+			{Title: "Intro", Start: 0, End: 12500 * time.Millisecond},
+			{Title: "Main", Start: 12500 * time.Millisecond, End: 635 * time.Second},
+		},
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("metadata = %+v, want %+v", metadata, want)
+	}
+}
+
+func TestParseVideoMetadataDuration(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"id":"a","duration_string":"45"}`, "0:45"},
+		{`{"id":"a","duration_string":"1:02:03"}`, "1:02:03"},
+		{`{"id":"a","duration":3725}`, "1:02:05"},
+		{`{"id":"a"}`, ""},
+	}
+	for _, tt := range tests {
+		metadata, err := parseVideoMetadata([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("parseVideoMetadata(%s): %v", tt.json, err)
+		}
+		if metadata.Duration != tt.want {
+			t.Errorf("parseVideoMetadata(%s).Duration = %q, want %q", tt.json, metadata.Duration, tt.want)
+		}
+	}
+}
+
+func TestParseVideoMetadataErrors(t *testing.T) {
+	for _, data := range []string{`not json`, `{"title":"no id"}`} {
+		if _, err := parseVideoMetadata([]byte(data)); err == nil {
+			t.Errorf("parseVideoMetadata(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestDownloadAudio(t *testing.T) {
+	var gotArgs []string
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		gotArgs = args
+		fmt.Fprintln(stdout, videoJSON)
+		fmt.Fprintln(stdout, "/tmp/out/abc123.m4a")
+		return nil
+	})
+
+	metadata, err := d.DownloadAudio(context.Background(), "https://youtu.be/abc123", "--cookies", "c.txt")
+	if err != nil {
+		t.Fatalf("DownloadAudio: %v", err)
+	}
+	if metadata.VideoID != "abc123" || metadata.Title != "A video" {
+		t.Errorf("metadata = %+v", metadata)
+	}
+	if metadata.AudioFilePath != "/tmp/out/abc123.m4a" {
+		t.Errorf("AudioFilePath = %q, want the last printed line", metadata.AudioFilePath)
+	}
+	if gotArgs[len(gotArgs)-1] != "https://youtu.be/abc123" {
+		t.Errorf("URL is not the last argument: %v", gotArgs)
+	}
+	if !slices.Contains(gotArgs, "--cookies") {
+		t.Errorf("extra options were not passed: %v", gotArgs)
+	}
+	if slices.Contains(gotArgs, "--progress-template") {
+		t.Errorf("progress requested without OnProgress: %v", gotArgs)
+	}
+}
+
+func TestDownloadAudioProgress(t *testing.T) {
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		if !slices.Contains(args, "--progress-template") {
+			t.Errorf("progress not requested: %v", args)
+		}
+		fmt.Fprintln(stderr, progressPrefix+" 500 1000 NA 250.5 2")
+		fmt.Fprintln(stderr, "WARNING: something")
+		fmt.Fprint(stderr, progressPrefix+" 1000 NA 1000 NA NA\n")
+		fmt.Fprintln(stdout, videoJSON)
+		fmt.Fprintln(stdout, "/tmp/out/abc123.m4a")
+		return nil
+	})
+	var updates []DownloadProgress
+	d.OnProgress = func(p DownloadProgress) {
+		updates = append(updates, p)
+	}
+
+	if _, err := d.DownloadAudio(context.Background(), "https://youtu.be/abc123"); err != nil {
+		t.Fatalf("DownloadAudio: %v", err)
+	}
+	want := []DownloadProgress{
+		{DownloadedBytes: 500, TotalBytes: 1000, Speed: 250.5, ETA: 2 * time.Second},
+		{DownloadedBytes: 1000, TotalBytes: 1000},
+	}
+	if !slices.Equal(updates, want) {
+		t.Errorf("progress updates = %+v, want %+v", updates, want)
+	}
+	if updates[0].Percent() != 50 {
+		t.Errorf("Percent() = %v, want 50", updates[0].Percent())
+	}
+}
+
+func TestDownloadAudioErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handle  func(args []string, stdout, stderr io.Writer) error
+		wantErr string
+	}{
+		{
+			name: "failing yt-dlp",
+			handle: func(args []string, stdout, stderr io.Writer) error {
+				fmt.Fprintln(stderr, "ERROR: Video unavailable")
+				return errors.New("exit status 1")
+			},
+			wantErr: "Video unavailable",
+		},
+		{
+			name: "missing file path",
+			handle: func(args []string, stdout, stderr io.Writer) error {
+				fmt.Fprintln(stdout, videoJSON)
+				return nil
+			},
+			wantErr: "did not contain enough lines",
+		},
+		{
+			name: "invalid metadata",
+			handle: func(args []string, stdout, stderr io.Writer) error {
+				fmt.Fprintln(stdout, "{")
+				fmt.Fprintln(stdout, "/tmp/out/abc123.m4a")
+				return nil
+			},
+			wantErr: "failed to parse yt-dlp metadata",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := fakeYTDLP(t, tt.handle)
+			_, err := d.DownloadAudio(context.Background(), "https://youtu.be/abc123")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DownloadAudio error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingYTDLP(t *testing.T) {
+	d := &Downloader{
+		Runner: proc.RunnerFunc(func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+			return errors.New(`exec: "yt-dlp": executable file not found in $PATH`)
+		}),
+	}
+	if _, err := d.GetVideoMetadata(context.Background(), "https://youtu.be/abc123"); err == nil || !strings.Contains(err.Error(), "yt-dlp not found") {
+		t.Errorf("GetVideoMetadata error = %v, want yt-dlp not found", err)
+	}
+}
+
+func TestGetVideoMetadata(t *testing.T) {
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		if !slices.Contains(args, "--dump-json") {
+			t.Errorf("metadata not requested as JSON: %v", args)
+		}
+		fmt.Fprintln(stdout, videoJSON)
+		return nil
+	})
+	metadata, err := d.GetVideoMetadata(context.Background(), "https://youtu.be/abc123")
+	if err != nil {
+		t.Fatalf("GetVideoMetadata: %v", err)
+	}
+	if metadata.VideoID != "abc123" || metadata.AudioFilePath != "" {
+		t.Errorf("metadata = %+v", metadata)
+	}
+
+	empty := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error { return nil })
+	if _, err := empty.GetVideoMetadata(context.Background(), "https://youtu.be/abc123"); err == nil {
+		t.Error("GetVideoMetadata succeeded without output")
+	}
+}
+
+func TestGetYTDLPVersion(t *testing.T) {
+	d := fakeYTDLP(t, nil)
+	version, err := d.GetYTDLPVersion(context.Background())
+	if err != nil || version != "2025.01.01" {
+		t.Errorf("GetYTDLPVersion = %q, %v", version, err)
+	}
+}
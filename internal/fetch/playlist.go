@@ -0,0 +1,201 @@
+package fetch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Playlist holds the videos behind a URL. A URL of a single video resolves to a
+// playlist with one entry and IsPlaylist set to false.
+type Playlist struct {
+	ID         string
+	Title      string
+	IsPlaylist bool
+	Entries    []PlaylistEntry
+}
+
+// PlaylistEntry is a single video of a playlist or channel.
+type PlaylistEntry struct {
+	Extractor  string // yt-dlp extractor of the video, e.g. "youtube"
+	VideoID    string
+	Title      string
+	URL        string
+	Duration   string
+	UploadDate string // Empty if unknown
+}
+
+// PlaylistOptions limits which entries of a playlist or channel are returned.
+type PlaylistOptions struct {
+	// Only return the first N entries, which are the latest uploads for channels. 0 returns all entries.
+	Latest int
+	// Only return entries uploaded on or after this date (YYYYMMDD). Entries with an unknown date are skipped.
+	UploadedAfter string
+}
+
+// flatInfo is the subset of yt-dlp's --dump-single-json output used for playlists.
+type flatInfo struct {
+	Type           string     `json:"_type"`
+	IEKey          string     `json:"ie_key"`
+	ExtractorKey   string     `json:"extractor_key"`
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	URL            string     `json:"url"`
+	WebpageURL     string     `json:"webpage_url"`
+	Duration       float64    `json:"duration"`
+	DurationString string     `json:"duration_string"`
+	UploadDate     string     `json:"upload_date"`
+	Entries        []flatInfo `json:"entries"`
+}
+
+// ListPlaylist returns the videos of a playlist or channel without downloading them,
+// using yt-dlp's flat extraction. URLs pointing to a single video return that video.
+func (d *Downloader) ListPlaylist(ctx context.Context, playlistURL string, opts PlaylistOptions) (Playlist, error) {
+	info, err := d.dumpFlatInfo(ctx, playlistURL, opts)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	if info.Type != "playlist" {
+		entry := info.toEntry()
+		if entry.URL == "" {
+			entry.URL = playlistURL
+		}
+		if entry.VideoID == "" {
+			return Playlist{}, fmt.Errorf("yt-dlp did not return a video ID for %s", playlistURL)
+		}
+		return Playlist{ID: entry.VideoID, Title: entry.Title, Entries: []PlaylistEntry{entry}}, nil
+	}
+
+	entries, err := d.flattenEntries(ctx, info.Entries, opts, 1)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	var filtered []PlaylistEntry
+	for _, entry := range entries {
+		// Entries of some sites don't name their extractor, they use the playlist's one
+		if entry.Extractor == "" {
+			entry.Extractor = normalizeExtractor(info.ExtractorKey)
+		}
+		if opts.UploadedAfter != "" && (entry.UploadDate == "" || entry.UploadDate < opts.UploadedAfter) {
+			continue
+		}
+		filtered = append(filtered, entry)
+		if opts.Latest > 0 && len(filtered) == opts.Latest {
+			break
+		}
+	}
+
+	return Playlist{
+		ID:         info.ID,
+		Title:      info.Title,
+		IsPlaylist: true,
+		Entries:    filtered,
+	}, nil
+}
+
+// maxPlaylistDepth limits how deep nested playlists are expanded, e.g. channel → Playlists tab → playlist.
+const maxPlaylistDepth = 3
+
+// flattenEntries converts playlist entries to videos. Channel tabs (e.g. Videos, Playlists) are
+// returned by flat extraction as nested playlists or as URLs of further playlists, both are expanded
+// up to maxPlaylistDepth levels deep.
+func (d *Downloader) flattenEntries(ctx context.Context, infos []flatInfo, opts PlaylistOptions, depth int) ([]PlaylistEntry, error) {
+	var entries []PlaylistEntry
+	for _, info := range infos {
+		if info.Type != "playlist" && !strings.HasSuffix(info.IEKey, "Tab") {
+			entries = append(entries, info.toEntry())
+			continue
+		}
+		if depth >= maxPlaylistDepth {
+			continue
+		}
+
+		nested := info.Entries
+		if info.Type != "playlist" {
+			tab, err := d.dumpFlatInfo(ctx, info.URL, opts)
+			if err != nil {
+				return nil, err
+			}
+			nested = tab.Entries
+		}
+		videos, err := d.flattenEntries(ctx, nested, opts, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, videos...)
+	}
+	return entries, nil
+}
+
+func (d *Downloader) dumpFlatInfo(ctx context.Context, playlistURL string, opts PlaylistOptions) (flatInfo, error) {
+	var info flatInfo
+
+	if err := d.CheckYTDLP(ctx); err != nil {
+		return info, err
+	}
+
+	args := []string{
+		"--flat-playlist",
+		"--dump-single-json",
+		"--no-playlist", // URLs of a video inside a playlist resolve to the video
+		"--quiet",
+		"--no-warnings",
+		// Provide upload dates for channel entries, which flat extraction omits otherwise
+		"--extractor-args", "youtubetab:approximate_date",
+	}
+	if opts.Latest > 0 && opts.UploadedAfter == "" {
+		args = append(args, "--playlist-end", strconv.Itoa(opts.Latest))
+	}
+	args = append(args, playlistURL)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if err := d.run(ctx, args, &stdout, &stderr); err != nil {
+		return info, fmt.Errorf("failed to list playlist: %w\nStderr: %s", err, stderr.String())
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		return info, fmt.Errorf("failed to parse yt-dlp playlist output: %w", err)
+	}
+	return info, nil
+}
+
+func (f flatInfo) toEntry() PlaylistEntry {
+	entryURL := f.WebpageURL
+	if entryURL == "" {
+		entryURL = f.URL
+	}
+
+	duration := f.DurationString
+	if duration != "" {
+		duration = formatDuration(duration)
+	} else if f.Duration > 0 {
+		duration = formatSeconds(int(f.Duration))
+	}
+
+	return PlaylistEntry{
+		Extractor:  normalizeExtractor(cmp.Or(f.IEKey, f.ExtractorKey)),
+		VideoID:    f.ID,
+		Title:      f.Title,
+		URL:        entryURL,
+		Duration:   duration,
+		UploadDate: f.UploadDate,
+	}
+}
+
+// formatSeconds formats a number of seconds like yt-dlp's duration_string, e.g. "1:02:03" or "2:03".
+func formatSeconds(total int) string {
+	hours := total / 3600
+	minutes := total % 3600 / 60
+	seconds := total % 60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// channelJSON is a flat extraction of a channel: its Videos tab nested with the videos, its
+// Playlists tab nested with URLs of playlists to extract separately and a video listed directly.
+const channelJSON = `{"_type":"playlist","id":"UC1","title":"Channel","extractor_key":"YoutubeTab","entries":[
+	{"_type":"playlist","id":"UC1-videos","title":"Channel - Videos","entries":[
+		{"_type":"url","ie_key":"Youtube","id":"v1","title":"First","url":"https://www.youtube.com/watch?v=v1","duration":65,"upload_date":"20240301"},
+		{"_type":"url","ie_key":"Youtube","id":"v2","title":"Second","url":"https://www.youtube.com/watch?v=v2","duration_string":"1:02:03"}
+	]},
+	{"_type":"playlist","id":"UC1-playlists","title":"Channel - Playlists","entries":[
+		{"_type":"url","ie_key":"YoutubeTab","id":"PL1","title":"A playlist","url":"https://www.youtube.com/playlist?list=PL1"}
+	]},
+	{"_type":"url","ie_key":"Youtube","id":"v3","title":"Third","url":"https://www.youtube.com/watch?v=v3","upload_date":"20240101"}
+]}`
+
+// playlistJSON is the flat extraction of the playlist listed in the channel's Playlists tab.
+const playlistJSON = `{"_type":"playlist","id":"PL1","title":"A playlist","entries":[
+	{"_type":"url","ie_key":"Youtube","id":"v4","title":"Fourth","url":"https://www.youtube.com/watch?v=v4","upload_date":"20240201"}
+]}`
+
+func TestListPlaylistNested(t *testing.T) {
+	var listed []string
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		if !slices.Contains(args, "--flat-playlist") {
+			return fmt.Errorf("unexpected call: %v", args)
+		}
+		url := args[len(args)-1]
+		listed = append(listed, url)
+		switch url {
+		case "https://www.youtube.com/@channel":
+			io.WriteString(stdout, channelJSON)
+		case "https://www.youtube.com/playlist?list=PL1":
+			io.WriteString(stdout, playlistJSON)
+		default:
+			return fmt.Errorf("unexpected URL %s", url)
+		}
+		return nil
+	})
+
+	playlist, err := d.ListPlaylist(context.Background(), "https://www.youtube.com/@channel", PlaylistOptions{})
+	if err != nil {
+		t.Fatalf("ListPlaylist: %v", err)
+	}
+	if playlist.ID != "UC1" || playlist.Title != "Channel" || !playlist.IsPlaylist {
+		t.Errorf("playlist = %+v, want the channel", playlist)
+	}
+	want := []PlaylistEntry{
+		{Extractor: "youtube", VideoID: "v1", Title: "First", URL: "https://www.youtube.com/watch?v=v1", Duration: "1:05", UploadDate: "20240301"},
+		{Extractor: "youtube", VideoID: "v2", Title: "Second", URL: "https://www.youtube.com/watch?v=v2", Duration: "1:02:03"},
+		{Extractor: "youtube", VideoID: "v4", Title: "Fourth", URL: "https://www.youtube.com/watch?v=v4", UploadDate: "20240201"},
+		{Extractor: "youtube", VideoID: "v3", Title: "Third", URL: "https://www.youtube.com/watch?v=v3", UploadDate: "20240101"},
+	}
+	if !reflect.DeepEqual(playlist.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", playlist.Entries, want)
+	}
+	if wantListed := []string{"https://www.youtube.com/@channel", "https://www.youtube.com/playlist?list=PL1"}; !slices.Equal(listed, wantListed) {
+		t.Errorf("listed %v, want %v", listed, wantListed)
+	}
+
+	tests := []struct {
+		opts PlaylistOptions
+		want []string
+	}{
+		{PlaylistOptions{Latest: 2}, []string{"v1", "v2"}},
+		{PlaylistOptions{UploadedAfter: "20240115"}, []string{"v1", "v4"}},
+		{PlaylistOptions{Latest: 1, UploadedAfter: "20240115"}, []string{"v1"}},
+	}
+	for _, tt := range tests {
+		playlist, err := d.ListPlaylist(context.Background(), "https://www.youtube.com/@channel", tt.opts)
+		if err != nil {
+			t.Fatalf("ListPlaylist(%+v): %v", tt.opts, err)
+		}
+		var got []string
+		for _, entry := range playlist.Entries {
+			got = append(got, entry.VideoID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ListPlaylist(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestListPlaylistSingleVideo(t *testing.T) {
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		io.WriteString(stdout, videoJSON)
+		return nil
+	})
+
+	playlist, err := d.ListPlaylist(context.Background(), "https://youtu.be/abc123", PlaylistOptions{})
+	if err != nil {
+		t.Fatalf("ListPlaylist: %v", err)
+	}
+	if playlist.IsPlaylist || len(playlist.Entries) != 1 || playlist.Entries[0].VideoID != "abc123" || playlist.Entries[0].Extractor != "youtube" {
+		t.Errorf("playlist = %+v, want the single video", playlist)
+	}
+}
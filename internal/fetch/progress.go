@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressPrefix marks the progress lines printed by yt-dlp using progressTemplate.
+const progressPrefix = "yt-transcribe-progress"
+
+// progressTemplate makes yt-dlp print the download progress as space separated values.
+// Unknown values are printed as "NA".
+const progressTemplate = "download:" + progressPrefix +
+	" %(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s %(progress.speed)s %(progress.eta)s"
+
+// DownloadProgress is a progress update of a yt-dlp download.
+type DownloadProgress struct {
+	DownloadedBytes int64
+	TotalBytes      int64         // Estimated when the exact size is unknown, 0 if there is no estimate
+	Speed           float64       // Bytes per second, 0 if unknown
+	ETA             time.Duration // 0 if unknown
+}
+
+// Percent returns how much of the download is done, or 0 if the total size is unknown.
+func (p DownloadProgress) Percent() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	return min(100, float64(p.DownloadedBytes)/float64(p.TotalBytes)*100)
+}
+
+// parseProgressLine parses a line printed by yt-dlp using progressTemplate.
+func parseProgressLine(line string) (DownloadProgress, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), progressPrefix)
+	if !ok {
+		return DownloadProgress{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) != 5 {
+		return DownloadProgress{}, false
+	}
+
+	total := parseProgressValue(fields[1])
+	if total == 0 {
+		total = parseProgressValue(fields[2])
+	}
+	return DownloadProgress{
+		DownloadedBytes: int64(parseProgressValue(fields[0])),
+		TotalBytes:      int64(total),
+		Speed:           parseProgressValue(fields[3]),
+		ETA:             time.Duration(parseProgressValue(fields[4]) * float64(time.Second)),
+	}, true
+}
+
+// parseProgressValue parses a number printed by yt-dlp, returning 0 for "NA".
+func parseProgressValue(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// progressWriter passes yt-dlp output through to out, except for progress lines,
+// which are reported to onProgress. Writers sharing a mutex never report concurrently.
+type progressWriter struct {
+	out        io.Writer
+	onProgress func(DownloadProgress)
+	mu         *sync.Mutex
+	buf        []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buf[:i+1]
+		if progress, ok := parseProgressLine(string(line)); ok {
+			w.mu.Lock()
+			w.onProgress(progress)
+			w.mu.Unlock()
+		} else if _, err := w.out.Write(line); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any incomplete last line to out.
+func (w *progressWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf)
+	w.buf = nil
+	return err
+}
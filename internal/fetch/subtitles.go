@@ -0,0 +1,162 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// ErrNoSubtitles is returned when a video has no subtitles of the requested kind
+var ErrNoSubtitles = errors.New("no subtitles available")
+
+// DownloadSubtitles downloads the existing subtitles of a video in WebVTT format and parses them.
+// With automatic set, automatically generated captions (as offered by YouTube) are fetched
+// instead of human-authored ones. Language "auto" only accepts YouTube's original language track
+// of automatic captions, as other tracks may be in any language.
+// ErrNoSubtitles is returned if the video has no matching subtitles.
+func (d *Downloader) DownloadSubtitles(ctx context.Context, videoURL, language string, automatic bool) (transcript.Transcript, error) {
+	if isAutoLanguage(language) && !automatic {
+		// Human-authored subtitles are not marked with the original language
+		return transcript.Transcript{}, ErrNoSubtitles
+	}
+	if err := d.CheckYTDLP(ctx); err != nil {
+		return transcript.Transcript{}, err
+	}
+
+	subtitlesDir, err := os.MkdirTemp(d.OutputDir, "subtitles-*")
+	if err != nil {
+		return transcript.Transcript{}, fmt.Errorf("failed to create subtitles directory: %w", err)
+	}
+	defer os.RemoveAll(subtitlesDir)
+
+	writeFlag := "--write-subs"
+	source := transcript.SourceManualCaptions
+	if automatic {
+		writeFlag = "--write-auto-subs"
+		source = transcript.SourceAutoCaptions
+	}
+
+	args := []string{
+		"--skip-download",
+		writeFlag,
+		"--sub-langs", subtitleLanguages(language, automatic),
+		"--sub-format", "vtt",
+		"--output", filepath.Join(subtitlesDir, "%(id)s.%(ext)s"),
+		"--no-playlist",
+		"--quiet",
+		"--no-warnings",
+		videoURL,
+	}
+
+	var stderr bytes.Buffer
+	if err := d.run(ctx, args, io.Discard, &stderr); err != nil {
+		return transcript.Transcript{}, fmt.Errorf("failed to download subtitles: %w\nStderr: %s", err, stderr.String())
+	}
+
+	files, err := filepath.Glob(filepath.Join(subtitlesDir, "*.vtt"))
+	if err != nil {
+		return transcript.Transcript{}, ErrNoSubtitles
+	}
+	subtitlesFile, ok := selectSubtitles(files, language)
+	if !ok {
+		return transcript.Transcript{}, ErrNoSubtitles
+	}
+
+	data, err := os.ReadFile(subtitlesFile)
+	if err != nil {
+		return transcript.Transcript{}, fmt.Errorf("failed to read subtitles: %w", err)
+	}
+
+	t, err := transcript.ParseVTT(string(data))
+	if err != nil {
+		return transcript.Transcript{}, fmt.Errorf("failed to parse subtitles: %w", err)
+	}
+	if t.IsEmpty() {
+		return transcript.Transcript{}, ErrNoSubtitles
+	}
+	t.Source = source
+	return t, nil
+}
+
+// FetchCaptions returns the existing captions of a video according to the policy.
+// With language "auto", the captions in videoLanguage (the language from the video's metadata)
+// are used, or the original language track if that is unknown.
+// ErrNoSubtitles is returned if the policy is CaptionPolicyWhisper or no usable captions exist,
+// in which case the audio has to be transcribed.
+func (d *Downloader) FetchCaptions(ctx context.Context, videoURL, language, videoLanguage string, policy transcript.CaptionPolicy) (transcript.Transcript, error) {
+	if policy != transcript.CaptionPolicyManual && policy != transcript.CaptionPolicyAuto {
+		return transcript.Transcript{}, ErrNoSubtitles
+	}
+	if isAutoLanguage(language) {
+		// Subtitle tracks are named by the primary language, e.g. "en" for an "en-US" video
+		language, _, _ = strings.Cut(videoLanguage, "-")
+	}
+
+	t, err := d.DownloadSubtitles(ctx, videoURL, language, false)
+	if err == nil || !errors.Is(err, ErrNoSubtitles) || policy != transcript.CaptionPolicyAuto {
+		return t, err
+	}
+
+	return d.DownloadSubtitles(ctx, videoURL, language, true)
+}
+
+// subtitleLanguages builds the --sub-langs selection for a language code.
+func subtitleLanguages(language string, automatic bool) string {
+	if isAutoLanguage(language) {
+		// Automatic captions in the original language of the video
+		return ".*-orig"
+	}
+	if automatic {
+		return language + "-orig," + language
+	}
+	return language + "," + language + "-.*"
+}
+
+// selectSubtitles picks the subtitle file for the language from the downloaded files, named like
+// "id.en.vtt". The original language track is preferred, then the exact language and then its
+// regional variants. It reports false if no file is in the language.
+func selectSubtitles(files []string, language string) (string, bool) {
+	if i := slices.IndexFunc(files, isOriginalTrack); i >= 0 {
+		return files[i], true
+	}
+	if isAutoLanguage(language) {
+		return "", false
+	}
+
+	slices.Sort(files)
+	for _, file := range files {
+		if subtitlesLanguage(file) == language {
+			return file, true
+		}
+	}
+	for _, file := range files {
+		if strings.HasPrefix(subtitlesLanguage(file), language+"-") {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// subtitlesLanguage returns the language code of a subtitle file named like "id.en.vtt".
+func subtitlesLanguage(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".vtt")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// isOriginalTrack reports whether a subtitle file is YouTube's original language track.
+func isOriginalTrack(path string) bool {
+	return strings.HasSuffix(path, "-orig.vtt")
+}
+
+// isAutoLanguage reports whether a language code leaves the language to be detected.
+func isAutoLanguage(language string) bool {
+	return language == "" || language == "auto"
+}
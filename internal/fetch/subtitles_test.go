@@ -0,0 +1,134 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// writeSubtitles returns a fake yt-dlp handler that writes the given files next to the --output template.
+func writeSubtitles(t *testing.T, files map[string]string) func(args []string, stdout, stderr io.Writer) error {
+	return func(args []string, stdout, stderr io.Writer) error {
+		i := slices.Index(args, "--output")
+		if i < 0 {
+			t.Fatalf("no --output in %v", args)
+		}
+		dir := filepath.Dir(args[i+1])
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestFetchCaptionsSelectsVideoLanguage(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         map[string]string
+		language      string
+		videoLanguage string
+		want          string
+	}{
+		{
+			name:          "language of the video",
+			files:         map[string]string{"abc123.de.vtt": "Hallo", "abc123.en.vtt": "Hello", "abc123.fr.vtt": "Bonjour"},
+			language:      "auto",
+			videoLanguage: "en",
+			want:          "Hello",
+		},
+		{
+			name:          "regional variant of the video language",
+			files:         map[string]string{"abc123.de.vtt": "Hallo", "abc123.en-GB.vtt": "Hello"},
+			language:      "auto",
+			videoLanguage: "en-US",
+			want:          "Hello",
+		},
+		{
+			name:     "requested language",
+			files:    map[string]string{"abc123.en-GB.vtt": "Hello", "abc123.en.vtt": "Hi"},
+			language: "en",
+			want:     "Hi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]string)
+			for name, text := range tt.files {
+				files[name] = "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n" + text + "\n"
+			}
+			d := fakeYTDLP(t, writeSubtitles(t, files))
+
+			captions, err := d.FetchCaptions(context.Background(), "https://youtu.be/abc123", tt.language, tt.videoLanguage, transcript.CaptionPolicyManual)
+			if err != nil {
+				t.Fatalf("FetchCaptions: %v", err)
+			}
+			if captions.Source != transcript.SourceManualCaptions {
+				t.Errorf("Source = %q, want %q", captions.Source, transcript.SourceManualCaptions)
+			}
+			if len(captions.Segments) != 1 || captions.Segments[0].Text != tt.want {
+				t.Errorf("segments = %+v, want %q", captions.Segments, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchCaptionsUnknownLanguage(t *testing.T) {
+	var langs []string
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		langs = append(langs, args[slices.Index(args, "--sub-langs")+1])
+		return writeSubtitles(t, map[string]string{
+			"abc123.de.vtt": "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nHallo\n",
+		})(args, stdout, stderr)
+	})
+
+	// Without the language of the video, no track but the original one can be trusted
+	if _, err := d.FetchCaptions(context.Background(), "https://youtu.be/abc123", "auto", "", transcript.CaptionPolicyAuto); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("error = %v, want ErrNoSubtitles", err)
+	}
+	if !slices.Equal(langs, []string{".*-orig"}) {
+		t.Errorf("requested languages = %q, want only the original automatic track", langs)
+	}
+
+	d = fakeYTDLP(t, writeSubtitles(t, map[string]string{
+		"abc123.de.vtt":      "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nHallo\n",
+		"abc123.en-orig.vtt": "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nHello\n",
+	}))
+	captions, err := d.FetchCaptions(context.Background(), "https://youtu.be/abc123", "auto", "", transcript.CaptionPolicyAuto)
+	if err != nil {
+		t.Fatalf("FetchCaptions: %v", err)
+	}
+	if captions.Source != transcript.SourceAutoCaptions || len(captions.Segments) != 1 || captions.Segments[0].Text != "Hello" {
+		t.Errorf("captions = %+v, want the original automatic track", captions)
+	}
+}
+
+func TestFetchCaptionsFallsBackToAutomatic(t *testing.T) {
+	d := fakeYTDLP(t, func(args []string, stdout, stderr io.Writer) error {
+		if slices.Contains(args, "--write-auto-subs") {
+			return writeSubtitles(t, map[string]string{
+				"abc123.en.vtt": "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nAuto\n",
+			})(args, stdout, stderr)
+		}
+		return nil
+	})
+
+	if _, err := d.FetchCaptions(context.Background(), "https://youtu.be/abc123", "en", "", transcript.CaptionPolicyManual); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("manual policy error = %v, want ErrNoSubtitles", err)
+	}
+
+	captions, err := d.FetchCaptions(context.Background(), "https://youtu.be/abc123", "en", "", transcript.CaptionPolicyAuto)
+	if err != nil {
+		t.Fatalf("FetchCaptions: %v", err)
+	}
+	if captions.Source != transcript.SourceAutoCaptions {
+		t.Errorf("Source = %q, want %q", captions.Source, transcript.SourceAutoCaptions)
+	}
+}
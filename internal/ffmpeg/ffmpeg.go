@@ -1,40 +1,63 @@
 package ffmpeg
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"strings"
+
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/transcript"
 )
 
+// defaultBinary is the ffmpeg binary used when FFMPEG.Binary is empty, looked up on PATH.
+const defaultBinary = "ffmpeg"
+
 // FFMPEG wraps the ffmpeg command-line tool.
 // It provides methods to manipulate audio files.
-type FFMPEG struct{}
+type FFMPEG struct {
+	// Path of the ffmpeg binary, empty to look up ffmpeg on PATH
+	Binary string
+	// Runs ffmpeg, nil to run it as a process
+	Runner proc.Runner
+	// Optional, called with progress updates while TranscribeWithWhisperFilter runs
+	OnProgress func(Progress)
+}
 
 // NewFFMPEG creates a new FFMPEG instance
 func NewFFMPEG() (*FFMPEG, error) {
 	return &FFMPEG{}, nil
 }
 
+// run runs ffmpeg with the given arguments.
+func (f *FFMPEG) run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	runner := f.Runner
+	if runner == nil {
+		runner = proc.ExecRunner{}
+	}
+	return runner.Run(ctx, cmp.Or(f.Binary, defaultBinary), args, stdout, stderr)
+}
+
 // CheckFFMPEG verifies that ffmpeg is installed
-func (f *FFMPEG) CheckFFMPEG() error {
-	cmd := exec.Command("ffmpeg", "-version")
-	if err := cmd.Run(); err != nil {
+func (f *FFMPEG) CheckFFMPEG(ctx context.Context) error {
+	if err := f.run(ctx, []string{"-version"}, io.Discard, io.Discard); err != nil {
 		return fmt.Errorf("ffmpeg not found: %w", err)
 	}
 	return nil
 }
 
 // GetFFMPEGVersion retrieves the version of the ffmpeg package
-func (f *FFMPEG) GetFFMPEGVersion() (string, error) {
-	cmd := exec.Command("ffmpeg", "-version")
-	output, err := cmd.Output()
-	if err != nil {
+func (f *FFMPEG) GetFFMPEGVersion(ctx context.Context) (string, error) {
+	var output bytes.Buffer
+	if err := f.run(ctx, []string{"-version"}, &output, io.Discard); err != nil {
 		return "", fmt.Errorf("ffmpeg not found: %w", err)
 	}
 
 	// Parse first line to extract version info
-	lines := strings.Split(string(output), "\n")
+	lines := strings.Split(output.String(), "\n")
 	if len(lines) > 0 && strings.Contains(lines[0], "ffmpeg version") {
 		// Extract version from line like "ffmpeg version 4.4.2-0ubuntu0.22.04.1"
 		parts := strings.Fields(lines[0])
@@ -47,18 +70,20 @@ func (f *FFMPEG) GetFFMPEGVersion() (string, error) {
 }
 
 // FFmpeg whisper filter integration. Requires ffmpeg built with --enable-whisper (FFmpeg 8+)
-// TranscribeWithWhisperFilter runs the FFmpeg 'whisper' audio filter and returns the transcription text.
+// TranscribeWithWhisperFilter runs the FFmpeg 'whisper' audio filter and returns the timed transcript.
+//
+// The ffmpeg process is killed when the context is cancelled.
 //
 // Reference: https://ffmpeg.org/ffmpeg-filters.html#whisper-1
-func (f *FFMPEG) TranscribeWithWhisperFilter(inputFile, modelPath, language string, queue int) (string, error) {
-	if err := f.CheckFFMPEG(); err != nil {
-		return "", err
+func (f *FFMPEG) TranscribeWithWhisperFilter(ctx context.Context, inputFile, modelPath, language string, queue int) (transcript.Transcript, error) {
+	if err := f.CheckFFMPEG(ctx); err != nil {
+		return transcript.Transcript{}, err
 	}
 
 	// Create a temporary destination file to collect the transcription output from the filter
 	tmpFile, err := os.CreateTemp("", "ffmpeg-whisper-*.txt")
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file for transcription: %w", err)
+		return transcript.Transcript{}, fmt.Errorf("failed to create temp file for transcription: %w", err)
 	}
 	destPath := tmpFile.Name()
 	tmpFile.Close()
@@ -69,15 +94,40 @@ func (f *FFMPEG) TranscribeWithWhisperFilter(inputFile, modelPath, language stri
 	filter := fmt.Sprintf("whisper=model=%s:language=%s:queue=%d:destination=%s:format=srt", modelPath, language, queue, destPath)
 
 	// Run ffmpeg to process audio only (-vn) and write null output while the filter writes to destination
-	cmd := exec.Command("ffmpeg", "-i", inputFile, "-vn", "-af", filter, "-f", "null", "-", "-y")
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("failed to run ffmpeg whisper filter: %w\nffmpeg output: %s", err, string(out))
+	args := []string{"-i", inputFile, "-vn", "-af", filter}
+	if f.OnProgress != nil {
+		// Progress is printed to stderr next to the log, which contains the duration of the input
+		args = append(args, "-progress", "pipe:2", "-nostats")
+	}
+	args = append(args, "-f", "null", "-", "-y")
+
+	var out bytes.Buffer
+	var outWriter io.Writer = &out
+
+	var progress *progressWriter
+	if f.OnProgress != nil {
+		progress = &progressWriter{out: &out, onProgress: f.OnProgress}
+		outWriter = progress
+	}
+
+	err = f.run(ctx, args, outWriter, outWriter)
+	if progress != nil {
+		progress.Flush()
+	}
+	if err != nil {
+		return transcript.Transcript{}, fmt.Errorf("failed to run ffmpeg whisper filter: %w\nffmpeg output: %s", err, out.String())
 	}
 
 	// Read the transcription text
 	data, err := os.ReadFile(destPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read transcription output: %w", err)
+		return transcript.Transcript{}, fmt.Errorf("failed to read transcription output: %w", err)
+	}
+
+	t, err := transcript.ParseSRT(string(data))
+	if err != nil {
+		return transcript.Transcript{}, fmt.Errorf("failed to parse transcription output: %w", err)
 	}
-	return string(data), nil
+	t.Source = transcript.SourceWhisper
+	return t, nil
 }
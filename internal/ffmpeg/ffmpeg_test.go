@@ -0,0 +1,153 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+const srt = "1\n00:00:00,000 --> 00:00:02,500\nHello there\n\n2\n00:00:02,500 --> 00:00:05,000\nGeneral Kenobi\n"
+
+var destinationPattern = regexp.MustCompile(`destination=([^:]+):`)
+
+// fakeFFMPEG returns an FFMPEG running a fake ffmpeg. It answers -version itself and passes
+// every other call to handle.
+func fakeFFMPEG(t *testing.T, handle func(args []string, stdout, stderr io.Writer) error) *FFMPEG {
+	t.Helper()
+	return &FFMPEG{
+		Binary: "/opt/ffmpeg",
+		Runner: proc.RunnerFunc(func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+			if name != "/opt/ffmpeg" {
+				t.Errorf("ran %q, want the configured binary", name)
+			}
+			if slices.Equal(args, []string{"-version"}) {
+				io.WriteString(stdout, "ffmpeg version 8.0 Copyright (c) 2000-2025 the FFmpeg developers\n")
+				return nil
+			}
+			return handle(args, stdout, stderr)
+		}),
+	}
+}
+
+// writeTranscript writes srt to the destination of the whisper filter in args.
+func writeTranscript(t *testing.T, args []string) {
+	t.Helper()
+	i := slices.Index(args, "-af")
+	if i < 0 {
+		t.Fatalf("no -af in %v", args)
+	}
+	m := destinationPattern.FindStringSubmatch(args[i+1])
+	if m == nil {
+		t.Fatalf("no destination in filter %q", args[i+1])
+	}
+	if err := os.WriteFile(m[1], []byte(srt), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTranscribeWithWhisperFilter(t *testing.T) {
+	f := fakeFFMPEG(t, func(args []string, stdout, stderr io.Writer) error {
+		if args[0] != "-i" || args[1] != "in.m4a" {
+			t.Errorf("input is not the first argument: %v", args)
+		}
+		filter := args[slices.Index(args, "-af")+1]
+		for _, option := range []string{"model=model.bin", "language=en", "queue=10", "format=srt"} {
+			if !strings.Contains(filter, option) {
+				t.Errorf("filter %q does not contain %q", filter, option)
+			}
+		}
+		writeTranscript(t, args)
+		return nil
+	})
+
+	got, err := f.TranscribeWithWhisperFilter(context.Background(), "in.m4a", "model.bin", "en", 10)
+	if err != nil {
+		t.Fatalf("TranscribeWithWhisperFilter: %v", err)
+	}
+	want := []transcript.Segment{
+		{Start: 0, End: 2500 * time.Millisecond, Text: "Hello there"},
+		{Start: 2500 * time.Millisecond, End: 5 * time.Second, Text: "General Kenobi"},
+	}
+	if !slices.Equal(got.Segments, want) {
+		t.Errorf("segments = %+v, want %+v", got.Segments, want)
+	}
+	if got.Source != transcript.SourceWhisper {
+		t.Errorf("Source = %q, want %q", got.Source, transcript.SourceWhisper)
+	}
+}
+
+func TestTranscribeWithWhisperFilterProgress(t *testing.T) {
+	f := fakeFFMPEG(t, func(args []string, stdout, stderr io.Writer) error {
+		if !slices.Contains(args, "-progress") {
+			t.Errorf("progress not requested: %v", args)
+		}
+		fmt.Fprintln(stderr, "Input #0, mov,mp4,m4a, from 'in.m4a':")
+		fmt.Fprintln(stderr, "  Duration: 00:01:40.00, start: 0.000000, bitrate: 129 kb/s")
+		fmt.Fprint(stderr, "out_time_us=25000000\nout_time=00:00:25.000000\nspeed=N/A\nprogress=continue\n")
+		fmt.Fprint(stderr, "out_time_us=50000000\nout_time=00:00:50.000000\nspeed=5x\nprogress=continue\n")
+		fmt.Fprint(stderr, "out_time_us=99000000\nspeed=5x\nprogress=end\n")
+		writeTranscript(t, args)
+		return nil
+	})
+	var updates []Progress
+	f.OnProgress = func(p Progress) {
+		updates = append(updates, p)
+	}
+
+	if _, err := f.TranscribeWithWhisperFilter(context.Background(), "in.m4a", "model.bin", "auto", 15); err != nil {
+		t.Fatalf("TranscribeWithWhisperFilter: %v", err)
+	}
+	total := 100 * time.Second
+	want := []Progress{
+		{Processed: 25 * time.Second, Total: total},
+		{Processed: 50 * time.Second, Total: total, Speed: 5},
+		{Processed: total, Total: total, Speed: 5},
+	}
+	if !slices.Equal(updates, want) {
+		t.Fatalf("progress updates = %+v, want %+v", updates, want)
+	}
+	if updates[1].Percent() != 50 || updates[1].ETA() != 10*time.Second {
+		t.Errorf("Percent() = %v, ETA() = %v, want 50 and 10s", updates[1].Percent(), updates[1].ETA())
+	}
+	if updates[0].ETA() != 0 {
+		t.Errorf("ETA() = %v without a known speed, want 0", updates[0].ETA())
+	}
+}
+
+func TestTranscribeWithWhisperFilterError(t *testing.T) {
+	f := fakeFFMPEG(t, func(args []string, stdout, stderr io.Writer) error {
+		fmt.Fprint(stderr, "out_time_us=0\nprogress=continue\n")
+		fmt.Fprintln(stderr, "[whisper] Failed to load model")
+		return errors.New("exit status 1")
+	})
+	f.OnProgress = func(Progress) {}
+
+	_, err := f.TranscribeWithWhisperFilter(context.Background(), "in.m4a", "missing.bin", "auto", 15)
+	if err == nil {
+		t.Fatal("TranscribeWithWhisperFilter succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "Failed to load model") {
+		t.Errorf("error %q does not contain the ffmpeg output", err)
+	}
+	if strings.Contains(err.Error(), "out_time_us") {
+		t.Errorf("error %q contains progress output", err)
+	}
+}
+
+func TestGetFFMPEGVersion(t *testing.T) {
+	f := fakeFFMPEG(t, nil)
+	version, err := f.GetFFMPEGVersion(context.Background())
+	if err != nil || version != "8.0" {
+		t.Errorf("GetFFMPEGVersion = %q, %v, want 8.0", version, err)
+	}
+}
@@ -0,0 +1,114 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is a progress update of an ffmpeg transcription.
+type Progress struct {
+	Processed time.Duration // Audio processed so far
+	Total     time.Duration // Duration of the input, 0 if unknown
+	Speed     float64       // Processing speed relative to playback, e.g. 2 for twice as fast, 0 if unknown
+}
+
+// Percent returns how much of the input is processed, or 0 if its duration is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return min(100, float64(p.Processed)/float64(p.Total)*100)
+}
+
+// ETA returns the estimated time remaining, or 0 if it cannot be estimated yet.
+func (p Progress) ETA() time.Duration {
+	if p.Total <= 0 || p.Speed <= 0 || p.Processed >= p.Total {
+		return 0
+	}
+	return time.Duration(float64(p.Total-p.Processed) / p.Speed)
+}
+
+// progressKeyPattern matches the key=value lines printed by ffmpeg's -progress option.
+var progressKeyPattern = regexp.MustCompile(`^\w+=\S*$`)
+
+// durationPattern matches the duration of the input in ffmpeg's log, e.g. "Duration: 00:10:35.12,".
+var durationPattern = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// progressWriter passes ffmpeg's log output through to out, except for the -progress key=value lines.
+// Each block of them is reported to onProgress, together with the input duration from the log.
+type progressWriter struct {
+	out        io.Writer
+	onProgress func(Progress)
+	buf        []byte
+	progress   Progress
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buf[:i+1]
+		if !w.parseLine(strings.TrimSpace(string(line))) {
+			if _, err := w.out.Write(line); err != nil {
+				return 0, err
+			}
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// parseLine updates the progress from a line of output and reports whether it was a progress line.
+func (w *progressWriter) parseLine(line string) bool {
+	if w.progress.Total == 0 {
+		if m := durationPattern.FindStringSubmatch(line); m != nil {
+			hours, _ := strconv.Atoi(m[1])
+			minutes, _ := strconv.Atoi(m[2])
+			seconds, _ := strconv.ParseFloat(m[3], 64)
+			w.progress.Total = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+				time.Duration(seconds*float64(time.Second))
+		}
+	}
+
+	if !progressKeyPattern.MatchString(line) {
+		return false
+	}
+	key, value, _ := strings.Cut(line, "=")
+	switch key {
+	case "out_time_us":
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			w.progress.Processed = time.Duration(us) * time.Microsecond
+		}
+	case "speed":
+		// Printed as e.g. "1.5x", or "N/A" before the speed is known
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+		if err != nil {
+			speed = 0
+		}
+		w.progress.Speed = speed
+	case "progress":
+		// The last key of each block, "continue" or "end"
+		if value == "end" && w.progress.Total > 0 {
+			w.progress.Processed = w.progress.Total
+		}
+		w.onProgress(w.progress)
+	}
+	return true
+}
+
+// Flush writes any incomplete last line to out.
+func (w *progressWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf)
+	w.buf = nil
+	return err
+}
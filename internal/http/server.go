@@ -1,22 +1,116 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/exler/yt-transcribe/internal/export"
 	"github.com/exler/yt-transcribe/internal/fetch"
+	"github.com/exler/yt-transcribe/internal/llm"
+	"github.com/exler/yt-transcribe/internal/proc"
 	"github.com/exler/yt-transcribe/internal/queue"
+	"github.com/exler/yt-transcribe/internal/subscription"
+	"github.com/exler/yt-transcribe/internal/transcript"
 )
 
-type Server struct{}
+// ServerConfig holds the dependencies and settings of the HTTP server.
+type ServerConfig struct {
+	Subscriptions *subscription.Manager
+	Poller        *subscription.Poller
 
-func NewServer() (*Server, error) {
-	return &Server{}, nil
+	// Directory uploaded files are stored in, one subdirectory per job.
+	UploadDir string
+	// Maximum size of an uploaded file in bytes.
+	MaxUploadSize int64
+
+	// Summary styles offered when adding a job or summary.
+	Styles *llm.Styles
+	// Whether an LLM is configured, so additional summaries can be requested.
+	Summarize bool
+	// Answers questions about transcripts, nil if no LLM is configured.
+	Answerer llm.Answerer
+
+	// Path of the yt-dlp binary used to resolve added URLs, empty to look it up on PATH.
+	YTDLPPath string
+	// Runs yt-dlp, nil to run it as a process.
+	Runner proc.Runner
+}
+
+type Server struct {
+	subscriptions *subscription.Manager
+	poller        *subscription.Poller
+
+	uploadDir     string
+	maxUploadSize int64
+
+	styles    *llm.Styles
+	summarize bool
+	answerer  llm.Answerer
+
+	ytdlpPath string
+	runner    proc.Runner
+}
+
+func NewServer(cfg ServerConfig) (*Server, error) {
+	if cfg.UploadDir == "" {
+		return nil, errors.New("upload directory is required")
+	}
+	if cfg.MaxUploadSize <= 0 {
+		return nil, errors.New("maximum upload size must be positive")
+	}
+	if cfg.Styles == nil {
+		return nil, errors.New("summary styles are required")
+	}
+
+	return &Server{
+		subscriptions: cfg.Subscriptions,
+		poller:        cfg.Poller,
+		uploadDir:     cfg.UploadDir,
+		maxUploadSize: cfg.MaxUploadSize,
+		styles:        cfg.Styles,
+		summarize:     cfg.Summarize,
+		answerer:      cfg.Answerer,
+		ytdlpPath:     cfg.YTDLPPath,
+		runner:        cfg.Runner,
+	}, nil
+}
+
+// newDownloader creates a downloader for listing videos that uses the configured yt-dlp.
+func (s *Server) newDownloader() (*fetch.Downloader, error) {
+	downloader, err := fetch.NewDownloader("") // OutputDir not used by ListPlaylist
+	if err != nil {
+		return nil, err
+	}
+	downloader.Binary = s.ytdlpPath
+	downloader.Runner = s.runner
+	return downloader, nil
+}
+
+// indexData returns the data of the index page without any messages.
+func (s *Server) indexData() pageData {
+	return pageData{SummaryStyles: s.styles.Names()}
+}
+
+// parseSummaryStyle validates the optional summary style of the queue forms.
+func (s *Server) parseSummaryStyle(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	return name, s.styles.Validate(name)
 }
 
 func (s *Server) IndexHandler(w http.ResponseWriter, r *http.Request) {
-	data := pageData{}
+	data := s.indexData()
 
 	if r.Method != http.MethodGet && r.Method != http.MethodPost {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
@@ -28,24 +122,47 @@ func (s *Server) IndexHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	youtubeURL := r.FormValue("youtube_url")
+	videoURL := r.FormValue("url")
+
+	if videoURL == "" {
+		data.QueueAddErrorMessage = "URL is required."
+		renderTemplate(w, "index", data)
+		return
+	}
+
+	playlistOpts, err := parsePlaylistOptions(r.FormValue("latest"), r.FormValue("uploaded_after"))
+	if err != nil {
+		data.QueueAddErrorMessage = err.Error()
+		renderTemplate(w, "index", data)
+		return
+	}
 
-	if youtubeURL == "" {
-		data.QueueAddErrorMessage = "YouTube URL is required."
+	var captionPolicy transcript.CaptionPolicy
+	if name := r.FormValue("caption_policy"); name != "" {
+		if captionPolicy, err = transcript.ParseCaptionPolicy(name); err != nil {
+			data.QueueAddErrorMessage = err.Error()
+			renderTemplate(w, "index", data)
+			return
+		}
+	}
+
+	summaryStyle, err := s.parseSummaryStyle(r.FormValue("summary_style"))
+	if err != nil {
+		data.QueueAddErrorMessage = err.Error()
 		renderTemplate(w, "index", data)
 		return
 	}
 
-	downloader, err := fetch.NewYouTubeDownloader("") // OutputDir not used by GetVideoMetadata
+	downloader, err := s.newDownloader()
 	if err != nil {
-		log.Printf("Error initializing YouTube downloader: %v", err)
-		data.QueueAddErrorMessage = "Failed to initialize YouTube downloader."
+		log.Printf("Error initializing downloader: %v", err)
+		data.QueueAddErrorMessage = "Failed to initialize downloader."
 		renderTemplate(w, "index", data)
 		return
 	}
 
-	// Call the new GetVideoMetadata function which only fetches metadata
-	videoMeta, err := downloader.GetVideoMetadata(youtubeURL)
+	// Resolves to a single entry for video URLs and to all matching entries for playlists and channels
+	playlist, err := downloader.ListPlaylist(r.Context(), videoURL, playlistOpts)
 	if err != nil {
 		log.Printf("Error fetching video metadata: %v", err)
 		data.QueueAddErrorMessage = "Failed to fetch video metadata."
@@ -53,24 +170,205 @@ func (s *Server) IndexHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !playlist.IsPlaylist {
+		entry := playlist.Entries[0]
+		videoInfo, err := queue.Add(queue.NewVideoInfo{
+			VideoURL:      videoURL,
+			Extractor:     entry.Extractor,
+			VideoID:       entry.VideoID,
+			Title:         entry.Title,
+			Duration:      entry.Duration,
+			UploadDate:    entry.UploadDate,
+			CaptionPolicy: captionPolicy,
+			SummaryStyle:  summaryStyle,
+		})
+		if err != nil {
+			log.Printf("Error adding video to queue: %v (URL: %s)", err, videoURL)
+			data.QueueAddErrorMessage = err.Error()
+		} else {
+			log.Printf("Video added to queue: ID %s, Title: %s", videoInfo.ID, videoInfo.Title)
+			data.QueueAddSuccessMessage = "Video '" + videoInfo.Title + "' added to queue successfully!"
+		}
+
+		renderTemplate(w, "index", data)
+		return
+	}
+
+	added, skipped := 0, 0
+	for _, entry := range playlist.Entries {
+		_, err := queue.Add(queue.NewVideoInfo{
+			VideoURL:      entry.URL,
+			Extractor:     entry.Extractor,
+			VideoID:       entry.VideoID,
+			Title:         entry.Title,
+			Duration:      entry.Duration,
+			UploadDate:    entry.UploadDate,
+			CaptionPolicy: captionPolicy,
+			SummaryStyle:  summaryStyle,
+		})
+		if err != nil {
+			skipped++
+			continue
+		}
+		added++
+	}
+	log.Printf("Playlist added to queue: ID %s, Title: %s, %d added, %d skipped", playlist.ID, playlist.Title, added, skipped)
+
+	if added == 0 && skipped == 0 {
+		data.QueueAddErrorMessage = "No videos in '" + playlist.Title + "' matched the filters."
+	} else {
+		data.QueueAddSuccessMessage = fmt.Sprintf("Added %d videos from '%s' to queue (%d already queued).", added, playlist.Title, skipped)
+	}
+
+	renderTemplate(w, "index", data)
+}
+
+// parsePlaylistOptions parses the optional playlist limits of the queue form.
+// The upload date is expected in the YYYY-MM-DD format of date inputs.
+func parsePlaylistOptions(latest string, uploadedAfter string) (fetch.PlaylistOptions, error) {
+	var opts fetch.PlaylistOptions
+
+	if latest != "" {
+		n, err := strconv.Atoi(latest)
+		if err != nil || n < 0 {
+			return opts, errors.New("latest videos must be a positive number")
+		}
+		opts.Latest = n
+	}
+
+	if uploadedAfter != "" {
+		date, err := time.Parse("2006-01-02", uploadedAfter)
+		if err != nil {
+			return opts, errors.New("uploaded after must be a date")
+		}
+		opts.UploadedAfter = date.Format("20060102")
+	}
+
+	return opts, nil
+}
+
+// UploadHandler adds an uploaded audio or video file to the queue. The file is stored in
+// a directory of its own inside the upload directory until the job is removed.
+func (s *Server) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	data := s.indexData()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	tooLarge := fmt.Sprintf("File is too large, the limit is %d MB.", s.maxUploadSize>>20)
+
+	// Leave some room for the other parts of the multipart body
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			data.QueueAddErrorMessage = tooLarge
+		} else {
+			data.QueueAddErrorMessage = "A file to upload is required."
+		}
+		renderTemplate(w, "index", data)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > s.maxUploadSize {
+		data.QueueAddErrorMessage = tooLarge
+		renderTemplate(w, "index", data)
+		return
+	}
+
+	summaryStyle, err := s.parseSummaryStyle(r.FormValue("summary_style"))
+	if err != nil {
+		data.QueueAddErrorMessage = err.Error()
+		renderTemplate(w, "index", data)
+		return
+	}
+
+	videoID, err := newUploadID()
+	if err != nil {
+		log.Printf("Error generating upload ID: %v", err)
+		data.QueueAddErrorMessage = "Failed to store the uploaded file."
+		renderTemplate(w, "index", data)
+		return
+	}
+
+	title := uploadTitle(header.Filename)
+	jobDir := filepath.Join(s.uploadDir, queue.JobID(uploadExtractor, videoID))
+	sourceFile := uploadPath(jobDir, header.Filename)
+	if err := saveUpload(file, jobDir, sourceFile); err != nil {
+		log.Printf("Error storing uploaded file %q: %v", header.Filename, err)
+		data.QueueAddErrorMessage = "Failed to store the uploaded file."
+		renderTemplate(w, "index", data)
+		return
+	}
+
 	videoInfo, err := queue.Add(queue.NewVideoInfo{
-		VideoURL:   youtubeURL,
-		VideoID:    videoMeta.VideoID,
-		Title:      videoMeta.Title,
-		Duration:   videoMeta.Duration,
-		UploadDate: videoMeta.UploadDate,
+		Extractor:    uploadExtractor,
+		VideoID:      videoID,
+		Title:        title,
+		UploadDate:   time.Now().Format("20060102"),
+		SourceFile:   sourceFile,
+		SummaryStyle: summaryStyle,
 	})
 	if err != nil {
-		log.Printf("Error adding video to queue: %v (URL: %s)", err, youtubeURL)
+		log.Printf("Error adding uploaded file to queue: %v", err)
+		removeUpload(sourceFile)
 		data.QueueAddErrorMessage = err.Error()
-	} else {
-		log.Printf("Video added to queue: ID %s, Title: %s", videoInfo.VideoID, videoInfo.Title)
-		data.QueueAddSuccessMessage = "Video '" + videoInfo.Title + "' added to queue successfully!"
+		renderTemplate(w, "index", data)
+		return
 	}
 
+	log.Printf("Uploaded file added to queue: ID %s, Title: %s", videoInfo.ID, videoInfo.Title)
+	data.QueueAddSuccessMessage = "File '" + videoInfo.Title + "' added to queue successfully!"
 	renderTemplate(w, "index", data)
 }
 
+// queueEntry is the state of a queue entry as listed by the queue table. Results and paths
+// on the server are left out, the entry page shows the full entry.
+type queueEntry struct {
+	ID            string
+	Title         string
+	Duration      string
+	UploadDate    string
+	Status        queue.VideoStatus
+	Error         string
+	Progress      *queue.Progress
+	HasTranscript bool
+	Actions       []string // Queue actions available for the entry, see queueActions
+}
+
+func newQueueEntry(videoInfo *queue.VideoInfo) queueEntry {
+	return queueEntry{
+		ID:            videoInfo.ID,
+		Title:         videoInfo.Title,
+		Duration:      videoInfo.Duration,
+		UploadDate:    videoInfo.UploadDate,
+		Status:        videoInfo.Status,
+		Error:         videoInfo.Error,
+		Progress:      videoInfo.Progress,
+		HasTranscript: !videoInfo.Transcript.IsEmpty(),
+		Actions:       queueActions(videoInfo),
+	}
+}
+
+// queueActions returns the queue actions the UI offers for the video: "cancel", "retry",
+// "resummarize" (retry of the summary only) and "remove".
+func queueActions(videoInfo *queue.VideoInfo) []string {
+	switch {
+	case videoInfo.Status == queue.VideoStatusPending:
+		return []string{"cancel", "remove"}
+	case videoInfo.Status.IsInProgress():
+		return []string{"cancel"}
+	case videoInfo.Transcript.IsEmpty():
+		return []string{"retry", "remove"}
+	default:
+		return []string{"retry", "resummarize", "remove"}
+	}
+}
+
 func (s *Server) QueueDataHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -78,14 +376,11 @@ func (s *Server) QueueDataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	currentQueue := queue.GetAll()
-	w.Header().Set("Content-Type", "application/json")
-	jsonData, err := json.Marshal(currentQueue)
-	if err != nil {
-		log.Printf("Error marshalling queue data: %v", err)
-		http.Error(w, "Error preparing queue data", http.StatusInternalServerError)
-		return
+	entries := make([]queueEntry, 0, len(currentQueue))
+	for _, videoInfo := range currentQueue {
+		entries = append(entries, newQueueEntry(videoInfo))
 	}
-	w.Write(jsonData)
+	writeJSON(w, http.StatusOK, entries)
 }
 
 func (s *Server) EntryHandler(w http.ResponseWriter, r *http.Request) {
@@ -94,15 +389,9 @@ func (s *Server) EntryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	videoID := r.PathValue("videoID")
+	id := r.PathValue("id")
 
-	var found *queue.VideoInfo
-	for _, v := range queue.GetAll() {
-		if v.VideoID == videoID {
-			found = v
-			break
-		}
-	}
+	found := queue.Get(id)
 	if found == nil {
 		http.NotFound(w, r)
 		return
@@ -110,14 +399,414 @@ func (s *Server) EntryHandler(w http.ResponseWriter, r *http.Request) {
 
 	renderTemplate(w, "entry", pageData{
 		Title:                  found.Title,
-		VideoID:                found.VideoID,
+		ID:                     found.ID,
+		Extractor:              found.Extractor,
 		Duration:               found.Duration,
 		UploadDate:             found.UploadDate,
 		Transcript:             found.Transcript,
-		Summary:                found.Summary,
+		Summaries:              found.Summaries,
+		SummaryStyles:          s.styles.Names(),
+		CanSummarize:           s.summarize,
+		Conversation:           found.Conversation,
+		CanAsk:                 s.answerer != nil,
 		ErrorDetail:            found.Error,
 		Status:                 found.Status,
+		Details:                found.Details,
+		History:                found.History,
+		Actions:                queueActions(found),
 		QueueAddSuccessMessage: "",
 		QueueAddErrorMessage:   "",
 	})
 }
+
+// EntryStatusHandler returns the current state of a queue entry as JSON, e.g. to follow its progress.
+func (s *Server) EntryStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	found := queue.Get(r.PathValue("id"))
+	if found == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, newQueueEntry(found))
+}
+
+// entryEvent is the state of a queue entry the entry page follows while it is processed.
+type entryEvent struct {
+	Status         queue.VideoStatus
+	Progress       *queue.Progress
+	PartialSummary string
+}
+
+// EntryEventsHandler pushes the state of a queue entry as server-sent events whenever it changes,
+// e.g. to show its summary while it is written. The stream ends once the entry is no longer processed.
+func (s *Server) EntryEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if queue.Get(id) == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+
+	var last []byte
+	for {
+		// Taken before reading the entry, so no change in between is missed
+		changed := queue.Changed()
+		found := queue.Get(id)
+		if found == nil {
+			return
+		}
+
+		data, err := json.Marshal(entryEvent{
+			Status:         found.Status,
+			Progress:       found.Progress,
+			PartialSummary: found.PartialSummary,
+		})
+		if err != nil {
+			log.Printf("Error marshalling entry event: %v", err)
+			return
+		}
+		if !bytes.Equal(data, last) {
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			last = data
+		}
+		if found.Status != queue.VideoStatusPending && !found.Status.IsInProgress() {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ExportHandler serves the transcript or a summary of a video as a file,
+// e.g. /entry/{id}/transcript.srt or /entry/{id}/summary.md?index=1 (the latest summary without an index).
+func (s *Server) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind, formatName, ok := strings.Cut(r.PathValue("file"), ".")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	found := queue.Get(r.PathValue("id"))
+	if found == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data []byte
+	switch kind {
+	case "transcript":
+		if found.Transcript.IsEmpty() {
+			http.Error(w, "Transcript not available", http.StatusNotFound)
+			return
+		}
+		data, err = export.Transcript(found.Transcript, found.Title, format)
+	case "summary":
+		summary, ok := selectSummary(found.Summaries, r.FormValue("index"))
+		if !ok {
+			http.Error(w, "Summary not available", http.StatusNotFound)
+			return
+		}
+		if !format.SupportsSummary() {
+			http.NotFound(w, r)
+			return
+		}
+		data, err = export.Summary(summary.Text, found.Title, format)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error exporting %s of %s: %v", kind, found.ID, err)
+		http.Error(w, "Error preparing export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": export.Filename(found.Title, kind, format),
+	}))
+	w.Write(data)
+}
+
+// selectSummary returns the summary at the given index, or the latest one if the index is empty.
+func selectSummary(summaries []queue.Summary, index string) (queue.Summary, bool) {
+	if index == "" {
+		if len(summaries) == 0 {
+			return queue.Summary{}, false
+		}
+		return summaries[len(summaries)-1], true
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(summaries) {
+		return queue.Summary{}, false
+	}
+	return summaries[i], true
+}
+
+// SummariesHandler requeues a finished video to add a summary of its transcript written with
+// the form's "style" and "model", which default to the server settings when empty.
+func (s *Server) SummariesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.summarize {
+		http.Error(w, "Summarization is disabled", http.StatusConflict)
+		return
+	}
+
+	style, err := s.parseSummaryStyle(r.FormValue("style"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	model := strings.TrimSpace(r.FormValue("model"))
+
+	id := r.PathValue("id")
+	err = queue.RequestSummary(id, style, model)
+	if err == nil {
+		log.Printf("Summary requested: ID %s (style: %q, model: %q)", id, style, model)
+	}
+	writeQueueActionResult(w, err)
+}
+
+// maxQuestionLength limits the length of questions about transcripts in characters.
+const maxQuestionLength = 2000
+
+// AskHandler answers the form's "question" about the transcript of a video and adds it to the
+// video's conversation, which is sent along with the question so follow-up questions work.
+func (s *Server) AskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.answerer == nil {
+		http.Error(w, "Question answering is disabled", http.StatusConflict)
+		return
+	}
+
+	question := strings.TrimSpace(r.FormValue("question"))
+	if question == "" {
+		http.Error(w, "A question is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(question) > maxQuestionLength {
+		http.Error(w, fmt.Sprintf("Questions are limited to %d characters", maxQuestionLength), http.StatusBadRequest)
+		return
+	}
+
+	found := queue.Get(r.PathValue("id"))
+	if found == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if found.Transcript.IsEmpty() {
+		http.Error(w, "Transcript not available", http.StatusConflict)
+		return
+	}
+
+	history := make([]llm.Exchange, 0, len(found.Conversation))
+	for _, exchange := range found.Conversation {
+		history = append(history, llm.Exchange{Question: exchange.Question, Answer: exchange.Answer})
+	}
+	answer, err := s.answerer.AnswerQuestion(r.Context(), llmVideo(found), found.Transcript, history, question)
+	if err != nil {
+		log.Printf("Error answering question about %s: %v", found.ID, err)
+		http.Error(w, "Failed to answer the question", http.StatusBadGateway)
+		return
+	}
+
+	exchange := queue.Exchange{
+		Question:  question,
+		Answer:    answer.Text,
+		Model:     answer.Model,
+		Citations: answer.Citations,
+		AskedAt:   time.Now(),
+	}
+	if err := queue.AddExchange(found.ID, exchange); err != nil {
+		writeQueueActionResult(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, exchange)
+}
+
+// ClearConversationHandler removes the questions asked about the transcript of a video.
+func (s *Server) ClearConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeQueueActionResult(w, queue.ClearConversation(r.PathValue("id")))
+}
+
+func (s *Server) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	err := queue.Cancel(id)
+	if err == nil {
+		log.Printf("Video cancelled: ID %s", id)
+	}
+	writeQueueActionResult(w, err)
+}
+
+func (s *Server) RetryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	summaryOnly := r.FormValue("stage") == "summary"
+	err := queue.Retry(id, summaryOnly)
+	if err == nil {
+		log.Printf("Video requeued: ID %s (summary only: %t)", id, summaryOnly)
+	}
+	writeQueueActionResult(w, err)
+}
+
+func (s *Server) RemoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	found := queue.Get(id)
+	err := queue.Remove(id)
+	if err == nil {
+		log.Printf("Video removed from queue: ID %s", id)
+		if found.SourceFile != "" {
+			removeUpload(found.SourceFile)
+		}
+	}
+	writeQueueActionResult(w, err)
+}
+
+// writeQueueActionResult maps the result of a queue operation to an HTTP response.
+func writeQueueActionResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, queue.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, queue.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error performing queue operation: %v", err)
+		http.Error(w, "Queue operation failed", http.StatusInternalServerError)
+	}
+}
+
+// SubscriptionsHandler lists subscriptions on GET and subscribes to the form's "url" on POST.
+func (s *Server) SubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		subscriptions, err := s.subscriptions.List()
+		if err != nil {
+			log.Printf("Error loading subscriptions: %v", err)
+			http.Error(w, "Error loading subscriptions", http.StatusInternalServerError)
+			return
+		}
+		if subscriptions == nil {
+			subscriptions = []subscription.Subscription{}
+		}
+		writeJSON(w, http.StatusOK, subscriptions)
+	case http.MethodPost:
+		created, err := s.subscriptions.Add(r.FormValue("url"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Subscribed to %s (ID %s)", created.URL, created.ID)
+		writeJSON(w, http.StatusCreated, created)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) SubscriptionRemoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.subscriptions.Remove(id); err != nil {
+		writeSubscriptionError(w, err)
+		return
+	}
+	log.Printf("Unsubscribed: ID %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SubscriptionCheckHandler checks a subscription for new uploads right away.
+func (s *Server) SubscriptionCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	added, err := s.poller.Poll(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"added": added})
+}
+
+func writeSubscriptionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, subscription.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Printf("Error updating subscription: %v", err)
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error marshalling response: %v", err)
+		http.Error(w, "Error preparing response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
@@ -0,0 +1,301 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/exler/yt-transcribe/internal/llm"
+	"github.com/exler/yt-transcribe/internal/queue"
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+func newTestServer(t *testing.T, answerer llm.Answerer) *Server {
+	t.Helper()
+	styles, err := llm.LoadStyles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(ServerConfig{
+		UploadDir:     t.TempDir(),
+		MaxUploadSize: 1 << 20,
+		Styles:        styles,
+		Answerer:      answerer,
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return server
+}
+
+// postForm calls the handler with a POST request of the form for the video.
+func postForm(handler http.HandlerFunc, id string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/entry/"+id, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestAskHandler(t *testing.T) {
+	runWorker(t, &fakeTools{t: t}, "")
+	id := addTestUpload(t, "1", "")
+	waitFinished(t, id)
+
+	answerer, err := llm.NewAnswerer(llm.Config{Endpoint: fakeLLM(t), Model: "test"})
+	if err != nil {
+		t.Fatalf("NewAnswerer: %v", err)
+	}
+	server := newTestServer(t, answerer)
+
+	for _, question := range []string{"What is said?", "And then?"} {
+		w := postForm(server.AskHandler, id, url.Values{"question": {question}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("ask status = %d (%s), want 200", w.Code, w.Body)
+		}
+		var exchange queue.Exchange
+		if err := json.NewDecoder(w.Body).Decode(&exchange); err != nil {
+			t.Fatal(err)
+		}
+		// The fake LLM answers with the first line of the prompt
+		if exchange.Question != question || exchange.Answer != "Video Title: Uploaded 1" || exchange.Model != "test" {
+			t.Errorf("exchange = %+v, want the question and answer", exchange)
+		}
+	}
+	if conversation := queue.Get(id).Conversation; len(conversation) != 2 || conversation[1].Question != "And then?" {
+		t.Errorf("conversation = %+v, want both exchanges", conversation)
+	}
+
+	if w := postForm(server.AskHandler, id, url.Values{"question": {"  "}}); w.Code != http.StatusBadRequest {
+		t.Errorf("empty question status = %d, want 400", w.Code)
+	}
+	if w := postForm(server.AskHandler, "youtube-missing", url.Values{"question": {"Why?"}}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown video status = %d, want 404", w.Code)
+	}
+
+	if w := postForm(server.ClearConversationHandler, id, nil); w.Code != http.StatusNoContent {
+		t.Errorf("clear status = %d, want 204", w.Code)
+	}
+	if conversation := queue.Get(id).Conversation; len(conversation) != 0 {
+		t.Errorf("conversation = %+v after clearing, want none", conversation)
+	}
+}
+
+func TestIndexHandlerUsesConfiguredYTDLP(t *testing.T) {
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+	styles, err := llm.LoadStyles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(ServerConfig{
+		UploadDir:     t.TempDir(),
+		MaxUploadSize: 1 << 20,
+		Styles:        styles,
+		YTDLPPath:     "fake-yt-dlp",
+		Runner:        &fakeTools{t: t},
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	form := url.Values{"url": {"https://www.youtube.com/watch?v=abc123"}}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	server.IndexHandler(w, r)
+
+	if !strings.Contains(w.Body.String(), "added to queue successfully") {
+		t.Errorf("page does not report the added video:\n%s", w.Body)
+	}
+	if videoInfo := queue.Get("youtube-abc123"); videoInfo == nil || videoInfo.Title != "Full title" {
+		t.Errorf("queued video = %+v, want the video listed by the configured yt-dlp", videoInfo)
+	}
+}
+
+func TestAskHandlerDisabled(t *testing.T) {
+	server := newTestServer(t, nil)
+	if w := postForm(server.AskHandler, "youtube-abc123", url.Values{"question": {"Why?"}}); w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409 without an LLM", w.Code)
+	}
+}
+
+func TestQueueDataHandler(t *testing.T) {
+	runWorker(t, &fakeTools{t: t}, "")
+	id := waitFinished(t, addTestUpload(t, "1", "")).ID
+	server := newTestServer(t, nil)
+
+	w := httptest.NewRecorder()
+	server.QueueDataHandler(w, httptest.NewRequest(http.MethodGet, "/queue", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", w.Code, w.Body)
+	}
+	var entries []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0]["ID"] != id {
+		t.Fatalf("entries = %+v, want the finished upload", entries)
+	}
+	entry := entries[0]
+	if entry["Status"] != string(queue.VideoStatusCompleted) || entry["HasTranscript"] != true {
+		t.Errorf("entry = %+v, want a completed entry with a transcript", entry)
+	}
+	if actions, _ := json.Marshal(entry["Actions"]); string(actions) != `["retry","resummarize","remove"]` {
+		t.Errorf("actions = %s, want retry, resummarize and remove", actions)
+	}
+	for _, field := range []string{"SourceFile", "WorkDir", "AudioFilePath", "Transcript", "Summaries"} {
+		if _, ok := entry[field]; ok {
+			t.Errorf("entry exposes %s", field)
+		}
+	}
+}
+
+func TestQueueActions(t *testing.T) {
+	tests := []struct {
+		status        queue.VideoStatus
+		hasTranscript bool
+		want          []string
+	}{
+		{queue.VideoStatusPending, false, []string{"cancel", "remove"}},
+		{queue.VideoStatusTranscribing, false, []string{"cancel"}},
+		{queue.VideoStatusSummarizing, true, []string{"cancel"}},
+		{queue.VideoStatusDownloadFailed, false, []string{"retry", "remove"}},
+		{queue.VideoStatusSummaryFailed, true, []string{"retry", "resummarize", "remove"}},
+		{queue.VideoStatusCancelled, false, []string{"retry", "remove"}},
+	}
+	for _, tt := range tests {
+		videoInfo := &queue.VideoInfo{Status: tt.status}
+		if tt.hasTranscript {
+			videoInfo.Transcript = transcript.FromText("Said")
+		}
+		if got := queueActions(videoInfo); !slices.Equal(got, tt.want) {
+			t.Errorf("queueActions(%s, transcript %v) = %v, want %v", tt.status, tt.hasTranscript, got, tt.want)
+		}
+	}
+}
+
+func TestEntryEventsHandler(t *testing.T) {
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+	id := addTestVideo(t)
+	queue.GetNext()
+	if err := queue.Transition(id, queue.VideoStatusSummarizing, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer(t, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/entry/{id}/events", server.EntryEventsHandler)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	response, err := http.Get(ts.URL + "/entry/" + id + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want an event stream", contentType)
+	}
+
+	lines := bufio.NewScanner(response.Body)
+	nextEvent := func() (event entryEvent, ok bool) {
+		t.Helper()
+		for lines.Scan() {
+			if data, found := strings.CutPrefix(lines.Text(), "data: "); found {
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					t.Fatal(err)
+				}
+				return event, true
+			}
+		}
+		return event, false
+	}
+
+	if event, _ := nextEvent(); event.Status != queue.VideoStatusSummarizing || event.PartialSummary != "" {
+		t.Errorf("first event = %+v, want the current state", event)
+	}
+	if err := queue.SetPartialSummary(id, "The sum"); err != nil {
+		t.Fatal(err)
+	}
+	if event, _ := nextEvent(); event.PartialSummary != "The sum" {
+		t.Errorf("event = %+v, want the partial summary", event)
+	}
+
+	if err := queue.AddSummary(id, queue.Summary{Text: "The summary"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Transition(id, queue.VideoStatusCompleted, ""); err != nil {
+		t.Fatal(err)
+	}
+	// The partial summary is cleared when the summary is added, which may be pushed separately
+	event, _ := nextEvent()
+	for event.Status == queue.VideoStatusSummarizing {
+		event, _ = nextEvent()
+	}
+	if event.Status != queue.VideoStatusCompleted || event.PartialSummary != "" {
+		t.Errorf("last event = %+v, want the completed state without a partial summary", event)
+	}
+	if event, ok := nextEvent(); ok {
+		t.Errorf("got event %+v after the entry was completed, want the stream to end", event)
+	}
+}
+
+func TestRenderTemplateEscapesVideoFields(t *testing.T) {
+	w := httptest.NewRecorder()
+	renderTemplate(w, "entry", pageData{
+		Title:      `<script>alert("title")</script>`,
+		ID:         `youtube-x";alert(1);//`,
+		Status:     queue.VideoStatusCompleted,
+		Transcript: transcript.FromText("<b>said</b>"),
+		Details: queue.VideoDetails{
+			Channel:     `<img src=x onerror=alert(1)>`,
+			ChannelURL:  "javascript:alert(1)",
+			OriginalURL: `https://example.com/" onmouseover="alert(1)`,
+			Description: "</div><script>alert(2)</script>",
+			Tags:        []string{"<i>tag</i>"},
+		},
+		Conversation: []queue.Exchange{{Question: "<u>why</u>", Answer: "<s>because</s>"}},
+		CanAsk:       true,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	page := w.Body.String()
+	for _, raw := range []string{"<script>alert", "<img src=x", "<b>said", "<i>tag", "<u>why", "<s>because", `href="javascript:`, `" onmouseover="`, `x";alert(1)`} {
+		if strings.Contains(page, raw) {
+			t.Errorf("page contains unescaped %q", raw)
+		}
+	}
+}
+
+func TestUploadNames(t *testing.T) {
+	tests := []struct {
+		filename string
+		title    string
+		path     string
+	}{
+		{"talk.mp3", "talk", "job/source.mp3"},
+		{"<img src=x onerror=alert(1)>.mp3", "img src=x onerror=alert(1)", "job/source.mp3"},
+		{`C:\Users\me\Recording 1.m4a`, "Recording 1", "job/source.m4a"},
+		{"recording", "recording", "job/source"},
+		{"recording.", "recording", "job/source"},
+		{"<>.wav", "Uploaded file", "job/source.wav"},
+	}
+	for _, tt := range tests {
+		if got := uploadTitle(tt.filename); got != tt.title {
+			t.Errorf("uploadTitle(%q) = %q, want %q", tt.filename, got, tt.title)
+		}
+		if got := uploadPath("job", tt.filename); got != tt.path {
+			t.Errorf("uploadPath(%q) = %q, want %q", tt.filename, got, tt.path)
+		}
+	}
+}
@@ -26,6 +26,10 @@ window.renderStatusBadge = function renderStatusBadge(status) {
             cls = 'badge-progress';
             icon = '<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 512 512"><path d="M128 48v122.8h.2l-.2.2 85.3 85-85.3 85.2.2.2h-.2V464h256V341.4h-.2l.2-.2-85.3-85.2 85.3-85-.2-.2h.2V48H128zm213.3 303.9v71.5H170.7v-71.5l85.3-85.2 85.3 85.2zM256 245.4l-85.3-85.2V87.6h170.7v72.5L256 245.4z" fill="currentColor"/></svg>';
             break;
+        case 'cancelled':
+            cls = 'badge-info';
+            icon = '<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 512 512"><path d="M256 48C141.1 48 48 141.1 48 256s93.1 208 208 208 208-93.1 208-208S370.9 48 256 48zm0 398.7c-105.1 0-190.7-85.5-190.7-190.7 0-105.1 85.5-190.7 190.7-190.7 105.1 0 190.7 85.5 190.7 190.7 0 105.1-85.6 190.7-190.7 190.7z" fill="currentColor"/><path d="M160 240h192v32H160z" fill="currentColor"/></svg>';
+            break;
         case 'pending':
             cls = 'badge-pending';
             icon = '<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 512 512"><path d="M256 48C141.1 48 48 141.1 48 256s93.1 208 208 208 208-93.1 208-208S370.9 48 256 48zm0 398.7c-105.1 0-190.7-85.5-190.7-190.7 0-105.1 85.5-190.7 190.7-190.7 105.1 0 190.7 85.5 190.7 190.7 0 105.1-85.6 190.7-190.7 190.7z" fill="currentColor"/><path d="M256 256h-96v17.3h113.3V128H256z" fill="currentColor"/></svg>';
@@ -46,3 +50,101 @@ window.formatUploadDate = function formatUploadDate(dateStr) {
     }
     return dateStr; // Return original if not in expected format
 }
+
+// Formats a speed in bytes per second, e.g. "1.5 MiB/s".
+window.formatSpeed = function formatSpeed(bytesPerSecond) {
+    const units = ['B/s', 'KiB/s', 'MiB/s', 'GiB/s'];
+    let value = bytesPerSecond;
+    let unit = 0;
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+    return `${value.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+};
+
+// Formats a duration in nanoseconds, as durations are serialized by the server, e.g. "1:02:03" or "2:03".
+window.formatETA = function formatETA(nanoseconds) {
+    const total = Math.round(nanoseconds / 1e9);
+    const hours = Math.floor(total / 3600);
+    const minutes = Math.floor((total % 3600) / 60);
+    const seconds = String(total % 60).padStart(2, '0');
+    return hours > 0 ? `${hours}:${String(minutes).padStart(2, '0')}:${seconds}` : `${minutes}:${seconds}`;
+};
+
+// Renders a progress bar for the Progress of a queue entry, or nothing if there is none.
+window.renderProgress = function renderProgress(progress) {
+    if (!progress) {
+        return '';
+    }
+    const percent = Math.max(0, Math.min(100, progress.Percent || 0));
+    const details = [`${percent.toFixed(1)}%`];
+    if (progress.Speed > 0) {
+        details.push(window.formatSpeed(progress.Speed));
+    }
+    if (progress.ETA > 0) {
+        details.push(`ETA ${window.formatETA(progress.ETA)}`);
+    }
+    return `<div class="progress"><div class="progress-bar" style="width: ${percent}%"></div></div><div class="progress-text muted">${details.join(' · ')}</div>`;
+};
+
+// Reports whether a queue entry returned by /queue has a transcript.
+window.hasTranscript = function hasTranscript(item) {
+    return !!(item && item.HasTranscript);
+};
+
+const inProgressStatuses = ['pending', 'processing', 'fetching_metadata', 'downloading', 'transcribing', 'summarizing'];
+
+// Renders buttons for the queue actions available for an entry, as listed by the server.
+// Clicks are handled by handleQueueActionClick.
+window.renderQueueActions = function renderQueueActions(id, actions) {
+    const labels = {
+        cancel: 'Cancel',
+        retry: 'Retry',
+        resummarize: 'Re-summarize',
+        remove: 'Delete',
+    };
+    return (actions || []).map((action) => {
+        const cls = action === 'remove' ? 'btn btn-small btn-outline' : 'btn btn-small';
+        return `<button type="button" class="${cls}" data-id="${encodeURIComponent(id)}" data-action="${action}">${labels[action]}</button>`;
+    }).join('');
+};
+
+// Performs a queue action against the server. Resolves to true when the action succeeded.
+window.performQueueAction = async function performQueueAction(id, action) {
+    if (action === 'remove' && !window.confirm('Delete this entry and its transcript?')) {
+        return false;
+    }
+
+    const endpoint = action === 'resummarize' ? 'retry' : action;
+    const body = new URLSearchParams();
+    if (action === 'resummarize') {
+        body.set('stage', 'summary');
+    }
+
+    const response = await fetch(`/entry/${id}/${endpoint}`, { method: 'POST', body });
+    if (!response.ok) {
+        window.alert(`Failed to ${action} entry: ${await response.text()}`);
+        return false;
+    }
+    return true;
+};
+
+// Handles a click on a button rendered by renderQueueActions.
+// Returns the performed action, or null if nothing happened.
+window.handleQueueActionClick = async function handleQueueActionClick(event) {
+    const button = event.target.closest('button[data-action]');
+    if (!button) {
+        return null;
+    }
+
+    event.stopPropagation();
+    const action = button.dataset.action;
+    button.disabled = true;
+    try {
+        const ok = await window.performQueueAction(button.dataset.id, action);
+        return ok ? action : null;
+    } finally {
+        button.disabled = false;
+    }
+};
@@ -2,29 +2,43 @@ package http
 
 import (
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
-	"text/template"
 
 	"github.com/exler/yt-transcribe/internal/queue"
+	"github.com/exler/yt-transcribe/internal/transcript"
 )
 
 // pageData holds the data for the template.
 type pageData struct {
 	Title                  string
-	VideoID                string
+	ID                     string
+	Extractor              string
 	Duration               string
 	UploadDate             string
 	Status                 queue.VideoStatus
-	Transcript             string
-	Summary                string
-	ErrorDetail            string // For general errors
+	Details                queue.VideoDetails
+	Transcript             transcript.Transcript
+	Summaries              []queue.Summary
+	SummaryStyles          []string         // Styles offered by the forms
+	CanSummarize           bool             // Whether summaries can be written, i.e. an LLM is configured
+	Conversation           []queue.Exchange // Questions about the transcript, oldest first
+	CanAsk                 bool             // Whether questions about the transcript can be asked
+	ErrorDetail            string           // For general errors
+	History                []queue.StatusChange
+	Actions                []string // Queue actions available for the entry
 	QueueAddSuccessMessage string
 	QueueAddErrorMessage   string
 }
 
+// templateFuncs are the helper functions available in all templates.
+var templateFuncs = template.FuncMap{
+	"clock": transcript.FormatClock,
+}
+
 func renderTemplate(w http.ResponseWriter, templateName string, data interface{}) {
-	tmpl, err := template.ParseFS(TemplateFiles, fmt.Sprintf("templates/%s.html", templateName))
+	tmpl, err := template.New("").Funcs(templateFuncs).ParseFS(TemplateFiles, fmt.Sprintf("templates/%s.html", templateName))
 	if err != nil {
 		log.Printf("Error parsing template from FS: %v", err)
 		http.Error(w, "Template parse error", http.StatusInternalServerError)
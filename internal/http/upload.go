@@ -0,0 +1,88 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/exler/yt-transcribe/internal/export"
+)
+
+// uploadExtractor is the extractor of jobs created from uploaded files.
+const uploadExtractor = "upload"
+
+// newUploadID generates a random video ID for an uploaded file.
+func newUploadID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// uploadBaseName returns the base name of an uploaded file as sent by the browser, which may be a Windows path.
+func uploadBaseName(filename string) string {
+	return filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+}
+
+// uploadTitle derives a job title from the name of an uploaded file without its extension.
+// Markup and control characters are dropped, as the name is chosen by the uploader.
+func uploadTitle(filename string) string {
+	name := uploadBaseName(filename)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>"'&`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name = strings.TrimSpace(name); name == "" {
+		return "Uploaded file"
+	}
+	return name
+}
+
+// uploadPath returns the path an uploaded file is stored at in the job directory,
+// keeping the extension of its name if it has one, so ffmpeg can detect the format.
+func uploadPath(jobDir, filename string) string {
+	path := filepath.Join(jobDir, "source")
+	if ext := filepath.Ext(uploadBaseName(filename)); len(ext) > 1 {
+		path += export.SanitizeFilename(ext)
+	}
+	return path
+}
+
+// saveUpload writes an uploaded file to path inside the job directory.
+func saveUpload(src io.Reader, jobDir, path string) error {
+	if err := os.MkdirAll(jobDir, 0o755); err != nil {
+		return err
+	}
+
+	dst, err := os.Create(path)
+	if err != nil {
+		removeUpload(path)
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		removeUpload(path)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		removeUpload(path)
+		return err
+	}
+	return nil
+}
+
+// removeUpload deletes an uploaded file together with its job directory.
+func removeUpload(path string) {
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		log.Printf("Error removing uploaded file %s: %v", path, err)
+	}
+}
@@ -1,18 +1,68 @@
 package http
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/exler/yt-transcribe/internal/fetch"
 	"github.com/exler/yt-transcribe/internal/ffmpeg"
 	"github.com/exler/yt-transcribe/internal/llm"
+	"github.com/exler/yt-transcribe/internal/proc"
 	"github.com/exler/yt-transcribe/internal/queue"
+	"github.com/exler/yt-transcribe/internal/transcript"
 )
 
+// TranscriptionWorkerConfig holds the settings for the transcription worker pool.
+type TranscriptionWorkerConfig struct {
+	LLMEndpoint string
+	LLMToken    string
+	LLMModel    string
+	// Transcript chunking for long videos, see llm.Config.
+	LLMChunkTokens  int
+	LLMChunkOverlap int
+	// Prompt templates summaries can be written with, nil for the built-in ones.
+	LLMStyles *llm.Styles
+	// Style of jobs that don't specify their own, llm.DefaultStyle if empty.
+	SummaryStyle string
+
+	WhisperModelPath string
+	WhisperLanguage  string
+	WhisperQueueSize int
+
+	// Paths of the yt-dlp and ffmpeg binaries, empty to look them up on PATH.
+	YTDLPPath  string
+	FFMPEGPath string
+	// Runs yt-dlp and ffmpeg, nil to run them as processes.
+	Runner proc.Runner
+
+	// Default policy for using existing captions instead of Whisper.
+	CaptionPolicy transcript.CaptionPolicy
+
+	RecoveryPolicy queue.RecoveryPolicy
+
+	// Number of jobs processed at the same time.
+	Workers int
+	// Per-stage concurrency limits shared by all workers.
+	StageLimits StageLimits
+	// Per-stage time limits of each job.
+	StageTimeouts proc.StageTimeouts
+}
+
+// StageLimits caps how many jobs may be in each pipeline stage at once.
+// A zero value means the stage is only limited by the number of workers.
+type StageLimits struct {
+	Downloads      int
+	Transcriptions int
+	Summaries      int
+}
+
 type TranscriptionWorker struct {
 	ffmpeg *ffmpeg.FFMPEG
 	// Path to the `ggml` converted Whisper models.
@@ -24,105 +74,499 @@ type TranscriptionWorker struct {
 	// Maximum size that will be queued into the filter before processing the audio.
 	ffmpegQueueSize int
 
+	ytdlpPath string
+	runner    proc.Runner
+
+	// Policy for jobs that don't specify their own.
+	captionPolicy transcript.CaptionPolicy
+
 	summarizer llm.Summarizer
+	// Style for jobs that don't specify their own.
+	summaryStyle string
+
+	// What to do with jobs that were interrupted by a restart.
+	recoveryPolicy queue.RecoveryPolicy
+
+	workers int
+	// Semaphores limiting the number of jobs in each stage.
+	downloadSlots      chan struct{}
+	transcriptionSlots chan struct{}
+	summarySlots       chan struct{}
+
+	timeouts proc.StageTimeouts
 }
 
-func NewTranscriptionWorker(llmEndpoint, llmToken, llmModel, ffmpegWhisperModelPath, ffmpegTranscriptionLanguage string, ffmpegQueueSize int) (*TranscriptionWorker, error) {
-	if ffmpegWhisperModelPath == "" {
+func NewTranscriptionWorker(cfg TranscriptionWorkerConfig) (*TranscriptionWorker, error) {
+	if cfg.WhisperModelPath == "" {
 		return nil, errors.New("whisper model path is required")
 	}
-	if ffmpegTranscriptionLanguage == "" {
-		ffmpegTranscriptionLanguage = "auto"
+	if cfg.WhisperLanguage == "" {
+		cfg.WhisperLanguage = "auto"
+	}
+	if cfg.CaptionPolicy == "" {
+		cfg.CaptionPolicy = transcript.CaptionPolicyWhisper
+	}
+	if cfg.RecoveryPolicy == "" {
+		cfg.RecoveryPolicy = queue.RecoveryPolicyRequeue
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.SummaryStyle == "" {
+		cfg.SummaryStyle = llm.DefaultStyle
 	}
 
 	f, err := ffmpeg.NewFFMPEG()
 	if err != nil {
 		log.Fatalf("Failed to initialize ffmpeg: %v", err)
 	}
+	f.Binary = cfg.FFMPEGPath
+	f.Runner = cfg.Runner
 
-	summarizer, err := llm.NewSummarizer(llmEndpoint, llmToken, llmModel)
+	if cfg.LLMStyles == nil {
+		if cfg.LLMStyles, err = llm.LoadStyles(""); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.LLMStyles.Validate(cfg.SummaryStyle); err != nil {
+		return nil, err
+	}
+
+	summarizer, err := llm.NewSummarizer(llm.Config{
+		Endpoint:     cfg.LLMEndpoint,
+		Token:        cfg.LLMToken,
+		Model:        cfg.LLMModel,
+		ChunkTokens:  cfg.LLMChunkTokens,
+		ChunkOverlap: cfg.LLMChunkOverlap,
+		Styles:       cfg.LLMStyles,
+	})
 	if err != nil {
-		log.Fatalf("Failed to initialize summarizer: %v", err)
+		return nil, fmt.Errorf("failed to initialize summarizer: %w", err)
 	}
 
 	return &TranscriptionWorker{
 		summarizer:                  summarizer,
+		summaryStyle:                cfg.SummaryStyle,
 		ffmpeg:                      f,
-		ffmpegWhisperModelPath:      ffmpegWhisperModelPath,
-		ffmpegTranscriptionLanguage: ffmpegTranscriptionLanguage,
-		ffmpegQueueSize:             ffmpegQueueSize,
+		ffmpegWhisperModelPath:      cfg.WhisperModelPath,
+		ffmpegTranscriptionLanguage: cfg.WhisperLanguage,
+		ffmpegQueueSize:             cfg.WhisperQueueSize,
+		ytdlpPath:                   cfg.YTDLPPath,
+		runner:                      cfg.Runner,
+		captionPolicy:               cfg.CaptionPolicy,
+		recoveryPolicy:              cfg.RecoveryPolicy,
+		workers:                     cfg.Workers,
+		downloadSlots:               newStageSlots(cfg.StageLimits.Downloads, cfg.Workers),
+		transcriptionSlots:          newStageSlots(cfg.StageLimits.Transcriptions, cfg.Workers),
+		summarySlots:                newStageSlots(cfg.StageLimits.Summaries, cfg.Workers),
+		timeouts:                    cfg.StageTimeouts,
 	}, nil
 }
 
-func (w *TranscriptionWorker) RunTranscriptionWorker(ctx context.Context) {
-	log.Println("Transcription worker started...")
+// newStageSlots creates a semaphore for a pipeline stage.
+// Limits outside of 1..workers are clamped to the number of workers.
+func newStageSlots(limit, workers int) chan struct{} {
+	if limit < 1 || limit > workers {
+		limit = workers
+	}
+	return make(chan struct{}, limit)
+}
 
-	for {
-		videoInfo := queue.GetNext()
-		if videoInfo == nil {
-			time.Sleep(5 * time.Second)
+// acquireSlot blocks until a slot in the given stage is free or the context is done.
+func acquireSlot(ctx context.Context, slots chan struct{}) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func releaseSlot(slots chan struct{}) {
+	<-slots
+}
+
+// RecoverInterruptedJobs handles jobs left in an in-progress status by a previous run
+// and removes their orphaned temporary directories.
+func (w *TranscriptionWorker) RecoverInterruptedJobs() {
+	for _, videoInfo := range queue.RecoverInterrupted(w.recoveryPolicy) {
+		log.Printf("Recovered interrupted video ID: %s (was %s, policy: %s)", videoInfo.ID, videoInfo.Status, w.recoveryPolicy)
+
+		if videoInfo.WorkDir == "" {
 			continue
 		}
+		if err := os.RemoveAll(videoInfo.WorkDir); err != nil {
+			log.Printf("Error cleaning up orphaned temp directory for %s: %v", videoInfo.ID, err)
+		}
+	}
+}
 
-		log.Printf("Processing video ID: %s, Title: %s", videoInfo.VideoID, videoInfo.Title)
+func (w *TranscriptionWorker) RunTranscriptionWorker(ctx context.Context) {
+	log.Printf("Transcription worker started with %d worker(s)...", w.workers)
+
+	w.RecoverInterruptedJobs()
 
-		// Create a temporary directory for this video's processing
-		tempDir, err := os.MkdirTemp("", "yt-transcribe-worker-*")
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.runWorkerLoop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	log.Println("Transcription worker stopped")
+}
+
+// runWorkerLoop processes videos as they are added to the queue until the context is done.
+func (w *TranscriptionWorker) runWorkerLoop(ctx context.Context) {
+	for {
+		videoInfo, err := queue.WaitNext(ctx)
 		if err != nil {
-			log.Printf("Error creating temp directory for %s: %v", videoInfo.VideoID, err)
-			queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusFailed, "Failed to create temp directory: "+err.Error(), "", "")
-			continue
+			return
 		}
 
-		// Download audio
-		queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusDownloading, "", "", "")
-		downloader, err := fetch.NewYouTubeDownloader(tempDir)
-		if err != nil {
-			log.Printf("Error initializing downloader for %s: %v", videoInfo.VideoID, err)
-			queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusFailed, "Failed to initialize downloader: "+err.Error(), "", "")
-			continue
+		jobCtx, done := queue.JobContext(ctx, videoInfo.ID)
+		w.processVideo(jobCtx, videoInfo)
+		done()
+	}
+}
+
+// progressInterval limits how often the progress of a job is stored and pushed to open pages.
+const progressInterval = time.Second
+
+// progressUpdater returns a function storing the progress of a job in the given stage.
+// Updates arriving sooner than progressInterval after the last stored one are dropped, except for completion.
+func progressUpdater(id string, stage queue.VideoStatus) func(queue.Progress) {
+	var last time.Time
+	return func(progress queue.Progress) {
+		if time.Since(last) < progressInterval && progress.Percent < 100 {
+			return
+		}
+		last = time.Now()
+		if err := queue.SetProgress(id, stage, progress); err != nil && !errors.Is(err, queue.ErrInvalidState) {
+			log.Printf("Error storing progress of %s: %v", id, err)
 		}
+	}
+}
 
-		downloadedMetadata, err := downloader.DownloadAudio(videoInfo.VideoURL)
-		if err != nil {
-			log.Printf("Error downloading audio for %s: %v", videoInfo.VideoID, err)
-			queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusFailed, "Failed to download audio: "+err.Error(), "", "")
-			continue
+// partialSummaryInterval limits how often the partial summary of a job is stored while it is written.
+// It is shorter than progressInterval, so the summary appears on the page as it is written.
+const partialSummaryInterval = 250 * time.Millisecond
+
+// partialSummaryUpdater returns a function collecting the pieces of a summary as they are written
+// and storing the text so far, at most every partialSummaryInterval.
+func partialSummaryUpdater(id string) func(text string) {
+	var partial strings.Builder
+	var last time.Time
+	return func(text string) {
+		partial.WriteString(text)
+		if time.Since(last) < partialSummaryInterval {
+			return
 		}
-		queue.SetAudioPath(videoInfo.VideoID, downloadedMetadata.AudioFilePath)
-		log.Printf("Audio downloaded for %s to %s", videoInfo.VideoID, downloadedMetadata.AudioFilePath)
+		last = time.Now()
+		if err := queue.SetPartialSummary(id, partial.String()); err != nil && !errors.Is(err, queue.ErrInvalidState) {
+			log.Printf("Error storing partial summary of %s: %v", id, err)
+		}
+	}
+}
 
-		// Transcribe audio using FFmpeg whisper filter
-		queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusTranscribing, "", "", "")
+// updateVideo moves the video to the next stage unless its processing was cancelled
+// or the worker is shutting down. It reports whether processing should continue.
+func updateVideo(ctx context.Context, id string, status queue.VideoStatus) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err := queue.Transition(id, status, ""); err != nil {
+		log.Printf("Stopping processing of %s: %v", id, err)
+		return false
+	}
+	return true
+}
 
-		transcriptionText, err := w.ffmpeg.TranscribeWithWhisperFilter(downloadedMetadata.AudioFilePath, w.ffmpegWhisperModelPath, w.ffmpegTranscriptionLanguage, w.ffmpegQueueSize)
-		if err != nil {
-			log.Printf("Error transcribing audio for %s: %v", videoInfo.VideoID, err)
-			queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusFailed, "Failed to transcribe audio: "+err.Error(), "", "")
-			continue
+// failVideo marks the video as failed with the given status. When the video was cancelled or the worker
+// is shutting down the status is left untouched, so a shutdown leaves the job for RecoverInterruptedJobs
+// on the next start.
+func failVideo(ctx context.Context, id string, status queue.VideoStatus, message string) {
+	if ctx.Err() != nil {
+		log.Printf("Processing of %s interrupted", id)
+		return
+	}
+	if err := queue.Transition(id, status, message); err != nil {
+		log.Printf("Error marking %s as %s: %v", id, status, err)
+	}
+}
+
+func (w *TranscriptionWorker) processVideo(ctx context.Context, videoInfo *queue.VideoInfo) {
+	log.Printf("Processing video ID: %s, Title: %s", videoInfo.ID, videoInfo.Title)
+
+	// Videos retried for summarization only already have a transcript
+	videoTranscript := videoInfo.Transcript
+	if videoTranscript.IsEmpty() {
+		var ok bool
+		if videoTranscript, ok = w.transcribeVideo(ctx, videoInfo); !ok {
+			return
 		}
-		log.Printf("Audio transcribed for %s", videoInfo.VideoID)
+	} else {
+		log.Printf("Reusing existing transcript for %s", videoInfo.ID)
+	}
 
-		// Summarize transcript using LLM (if enabled)
-		queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusSummarizing, "", transcriptionText, "")
-		summaryText, err := w.summarizer.SummarizeText(ctx, videoInfo.Title, transcriptionText)
+	// Summarize transcript using LLM (if enabled)
+	if err := acquireSlot(ctx, w.summarySlots); err != nil {
+		return
+	}
+	if !updateVideo(ctx, videoInfo.ID, queue.VideoStatusSummarizing) {
+		releaseSlot(w.summarySlots)
+		return
+	}
+	summaryCtx, cancel := proc.WithTimeout(ctx, w.timeouts.Summary)
+	// The stored video has the metadata fetched since the job was picked up
+	summary, err := w.summarizer.SummarizeTranscript(summaryCtx, llmVideo(queue.Get(videoInfo.ID)), videoTranscript, llm.SummaryOptions{
+		Style:  cmp.Or(videoInfo.SummaryStyle, w.summaryStyle),
+		Model:  videoInfo.SummaryModel,
+		OnText: partialSummaryUpdater(videoInfo.ID),
+	})
+	err = proc.TimeoutError(summaryCtx, w.timeouts.Summary, err)
+	cancel()
+	releaseSlot(w.summarySlots)
+	if err != nil {
+		log.Printf("Error summarizing transcript for video ID %s: %v", videoInfo.ID, err)
+		failVideo(ctx, videoInfo.ID, queue.VideoStatusSummaryFailed, "Failed to summarize transcript: "+err.Error())
+		return
+	}
+	// Disabled summarization produces no summary
+	if summary.Text != "" {
+		err := queue.AddSummary(videoInfo.ID, queue.Summary{
+			Style:      summary.Style,
+			Model:      summary.Model,
+			PromptHash: summary.PromptHash,
+			CreatedAt:  time.Now(),
+			Text:       summary.Text,
+		})
 		if err != nil {
-			log.Printf("Error summarizing transcript for video ID %s: %v", videoInfo.VideoID, err)
-			queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusFailed, "Failed to summarize transcript: "+err.Error(), "", "")
-			continue
-		}
-		queue.UpdateItem(videoInfo.VideoID, queue.VideoStatusCompleted, "", transcriptionText, summaryText)
-		if summaryText != "" {
-			log.Printf("Transcript summarized for %s", videoInfo.VideoID)
-		} else {
-			log.Printf("Summarization disabled for %s", videoInfo.VideoID)
+			log.Printf("Error storing summary for %s: %v", videoInfo.ID, err)
+			return
 		}
+	}
+	if !updateVideo(ctx, videoInfo.ID, queue.VideoStatusCompleted) {
+		return
+	}
+	if summary.Text != "" {
+		log.Printf("Transcript summarized for %s (style: %s, model: %s)", videoInfo.ID, summary.Style, summary.Model)
+	} else {
+		log.Printf("Summarization disabled for %s", videoInfo.ID)
+	}
 
-		// Clean up temporary files
+	log.Printf("Successfully processed video ID: %s, Title: %s", videoInfo.ID, videoInfo.Title)
+}
+
+// transcribeVideo uses the existing captions of a video if the caption policy allows it,
+// otherwise it downloads the audio and transcribes it. It reports whether the transcription succeeded.
+func (w *TranscriptionWorker) transcribeVideo(ctx context.Context, videoInfo *queue.VideoInfo) (transcript.Transcript, bool) {
+	// Uploaded files are already local
+	if videoInfo.SourceFile != "" {
+		return w.transcribeAudio(ctx, videoInfo, videoInfo.SourceFile)
+	}
+
+	// Create a temporary directory for this video's processing
+	tempDir, err := os.MkdirTemp("", "yt-transcribe-worker-*")
+	if err != nil {
+		log.Printf("Error creating temp directory for %s: %v", videoInfo.ID, err)
+		failVideo(ctx, videoInfo.ID, queue.VideoStatusFailed, "Failed to create temp directory: "+err.Error())
+		return transcript.Transcript{}, false
+	}
+	queue.SetWorkDir(videoInfo.ID, tempDir)
+
+	// Clean up temporary files
+	defer func() {
 		if err := os.RemoveAll(tempDir); err != nil {
-			log.Printf("Error cleaning up temp directory for %s: %v", videoInfo.VideoID, err)
+			log.Printf("Error cleaning up temp directory for %s: %v", videoInfo.ID, err)
+		}
+		queue.SetWorkDir(videoInfo.ID, "")
+	}()
+
+	// Download audio
+	downloader, err := w.newDownloader(tempDir)
+	if err != nil {
+		log.Printf("Error initializing downloader for %s: %v", videoInfo.ID, err)
+		failVideo(ctx, videoInfo.ID, queue.VideoStatusFailed, "Failed to initialize downloader: "+err.Error())
+		return transcript.Transcript{}, false
+	}
+
+	if !w.fetchMetadata(ctx, downloader, videoInfo) {
+		return transcript.Transcript{}, false
+	}
+
+	if err := acquireSlot(ctx, w.downloadSlots); err != nil {
+		return transcript.Transcript{}, false
+	}
+	if !updateVideo(ctx, videoInfo.ID, queue.VideoStatusDownloading) {
+		releaseSlot(w.downloadSlots)
+		return transcript.Transcript{}, false
+	}
+
+	if captions, ok := w.fetchCaptions(ctx, downloader, videoInfo); ok {
+		releaseSlot(w.downloadSlots)
+		if !updateVideo(ctx, videoInfo.ID, queue.VideoStatusTranscribing) {
+			return transcript.Transcript{}, false
+		}
+		if err := queue.SetTranscript(videoInfo.ID, captions); err != nil {
+			log.Printf("Error storing transcript for %s: %v", videoInfo.ID, err)
+			return transcript.Transcript{}, false
+		}
+		return captions, true
+	}
+
+	updateProgress := progressUpdater(videoInfo.ID, queue.VideoStatusDownloading)
+	downloader.OnProgress = func(p fetch.DownloadProgress) {
+		updateProgress(queue.Progress{Percent: p.Percent(), Speed: p.Speed, ETA: p.ETA})
+	}
+	downloadCtx, cancel := proc.WithTimeout(ctx, w.timeouts.Download)
+	downloadedMetadata, err := downloader.DownloadAudio(downloadCtx, videoInfo.VideoURL)
+	err = proc.TimeoutError(downloadCtx, w.timeouts.Download, err)
+	cancel()
+	releaseSlot(w.downloadSlots)
+	if err != nil {
+		log.Printf("Error downloading audio for %s: %v", videoInfo.ID, err)
+		failVideo(ctx, videoInfo.ID, queue.VideoStatusDownloadFailed, "Failed to download audio: "+err.Error())
+		return transcript.Transcript{}, false
+	}
+	queue.SetAudioPath(videoInfo.ID, downloadedMetadata.AudioFilePath)
+	log.Printf("Audio downloaded for %s to %s", videoInfo.ID, downloadedMetadata.AudioFilePath)
+
+	return w.transcribeAudio(ctx, videoInfo, downloadedMetadata.AudioFilePath)
+}
+
+// newDownloader creates a downloader writing to outputDir that uses the configured yt-dlp.
+func (w *TranscriptionWorker) newDownloader(outputDir string) (*fetch.Downloader, error) {
+	downloader, err := fetch.NewDownloader(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	downloader.Binary = w.ytdlpPath
+	downloader.Runner = w.runner
+	return downloader, nil
+}
+
+// transcribeAudio transcribes a local audio or video file with the FFmpeg whisper filter
+// and stores the transcript. It reports whether the transcription succeeded.
+func (w *TranscriptionWorker) transcribeAudio(ctx context.Context, videoInfo *queue.VideoInfo, audioPath string) (transcript.Transcript, bool) {
+	if err := acquireSlot(ctx, w.transcriptionSlots); err != nil {
+		return transcript.Transcript{}, false
+	}
+	if !updateVideo(ctx, videoInfo.ID, queue.VideoStatusTranscribing) {
+		releaseSlot(w.transcriptionSlots)
+		return transcript.Transcript{}, false
+	}
+	// Use a copy of the shared FFMPEG instance to report the progress of this video only
+	ff := *w.ffmpeg
+	updateProgress := progressUpdater(videoInfo.ID, queue.VideoStatusTranscribing)
+	ff.OnProgress = func(p ffmpeg.Progress) {
+		updateProgress(queue.Progress{Percent: p.Percent(), ETA: p.ETA()})
+	}
+	transcribeCtx, cancel := proc.WithTimeout(ctx, w.timeouts.Transcription)
+	videoTranscript, err := ff.TranscribeWithWhisperFilter(transcribeCtx, audioPath, w.ffmpegWhisperModelPath, w.ffmpegTranscriptionLanguage, w.ffmpegQueueSize)
+	err = proc.TimeoutError(transcribeCtx, w.timeouts.Transcription, err)
+	cancel()
+	releaseSlot(w.transcriptionSlots)
+	if err != nil {
+		log.Printf("Error transcribing audio for %s: %v", videoInfo.ID, err)
+		failVideo(ctx, videoInfo.ID, queue.VideoStatusTranscriptionFailed, "Failed to transcribe audio: "+err.Error())
+		return transcript.Transcript{}, false
+	}
+	log.Printf("Audio transcribed for %s", videoInfo.ID)
+
+	// Store the transcript right away, so it survives a failure in the summarization stage
+	if err := queue.SetTranscript(videoInfo.ID, videoTranscript); err != nil {
+		log.Printf("Error storing transcript for %s: %v", videoInfo.ID, err)
+		return transcript.Transcript{}, false
+	}
+
+	return videoTranscript, true
+}
+
+// fetchCaptions returns the existing captions of a video if its caption policy allows them.
+// It reports false when the audio has to be transcribed with Whisper instead.
+func (w *TranscriptionWorker) fetchCaptions(ctx context.Context, downloader *fetch.Downloader, videoInfo *queue.VideoInfo) (transcript.Transcript, bool) {
+	policy := videoInfo.CaptionPolicy
+	if policy == "" {
+		policy = w.captionPolicy
+	}
+	if policy == transcript.CaptionPolicyWhisper {
+		return transcript.Transcript{}, false
+	}
+
+	// The metadata, including the language of the video, is stored after the video was taken from the queue
+	var videoLanguage string
+	if stored := queue.Get(videoInfo.ID); stored != nil {
+		videoLanguage = stored.Details.Language
+	}
+
+	captionsCtx, cancel := proc.WithTimeout(ctx, w.timeouts.Download)
+	defer cancel()
+	captions, err := downloader.FetchCaptions(captionsCtx, videoInfo.VideoURL, w.ffmpegTranscriptionLanguage, videoLanguage, policy)
+	err = proc.TimeoutError(captionsCtx, w.timeouts.Download, err)
+	if err != nil {
+		if errors.Is(err, fetch.ErrNoSubtitles) {
+			log.Printf("No usable captions for %s, falling back to Whisper", videoInfo.ID)
+		} else if ctx.Err() == nil {
+			log.Printf("Error fetching captions for %s, falling back to Whisper: %v", videoInfo.ID, err)
 		}
+		return transcript.Transcript{}, false
+	}
+
+	log.Printf("Using %s for %s", strings.ToLower(captions.Source.Label()), videoInfo.ID)
+	return captions, true
+}
 
-		log.Printf("Successfully processed video ID: %s, Title: %s", videoInfo.VideoID, videoInfo.Title)
+// fetchMetadata stores the full metadata of a video, as playlist entries only come with the basics.
+// It reports whether the metadata was fetched.
+func (w *TranscriptionWorker) fetchMetadata(ctx context.Context, downloader *fetch.Downloader, videoInfo *queue.VideoInfo) bool {
+	if !updateVideo(ctx, videoInfo.ID, queue.VideoStatusFetchingMetadata) {
+		return false
+	}
+	metadataCtx, cancel := proc.WithTimeout(ctx, w.timeouts.Metadata)
+	defer cancel()
+	metadata, err := downloader.GetVideoMetadata(metadataCtx, videoInfo.VideoURL)
+	err = proc.TimeoutError(metadataCtx, w.timeouts.Metadata, err)
+	if err != nil {
+		log.Printf("Error fetching metadata for %s: %v", videoInfo.ID, err)
+		failVideo(ctx, videoInfo.ID, queue.VideoStatusMetadataFailed, "Failed to fetch video metadata: "+err.Error())
+		return false
+	}
+
+	err = queue.SetMetadata(videoInfo.ID, metadata.Title, metadata.Duration, metadata.UploadDate, queue.VideoDetails{
+		Channel:      metadata.Channel,
+		ChannelURL:   metadata.ChannelURL,
+		Description:  metadata.Description,
+		Tags:         metadata.Tags,
+		Chapters:     metadata.Chapters,
+		ThumbnailURL: metadata.ThumbnailURL,
+		ViewCount:    metadata.ViewCount,
+		Language:     metadata.Language,
+		OriginalURL:  metadata.OriginalURL,
+	})
+	if err != nil {
+		log.Printf("Error storing metadata for %s: %v", videoInfo.ID, err)
+		return false
+	}
+	return true
+}
+
+// llmVideo describes a video for the LLM using its stored metadata.
+func llmVideo(videoInfo *queue.VideoInfo) llm.Video {
+	if videoInfo == nil {
+		return llm.Video{}
+	}
+	return llm.Video{
+		Title:       videoInfo.Title,
+		Channel:     videoInfo.Details.Channel,
+		Description: videoInfo.Details.Description,
+		Duration:    videoInfo.Duration,
+		UploadDate:  videoInfo.UploadDate,
+		URL:         cmp.Or(videoInfo.Details.OriginalURL, videoInfo.VideoURL),
+		Tags:        videoInfo.Details.Tags,
+		Chapters:    videoInfo.Details.Chapters,
 	}
 }
@@ -0,0 +1,611 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/proc"
+	"github.com/exler/yt-transcribe/internal/queue"
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+const testVideoJSON = `{"id":"abc123","extractor_key":"Youtube","title":"Full title","duration_string":"0:05",` +
+	`"upload_date":"20240505","channel":"Channel","description":"About the video","tags":["go"],"language":"en",` +
+	`"webpage_url":"https://www.youtube.com/watch?v=abc123"}`
+
+var testDestinationPattern = regexp.MustCompile(`destination=([^:]+):`)
+
+// fakeTools answers calls to yt-dlp and ffmpeg like the real tools would for a single short video,
+// without network access or a Whisper model.
+type fakeTools struct {
+	t           *testing.T
+	downloadErr error             // Returned by audio downloads if set
+	subtitles   map[string]string // Subtitle files written by subtitle downloads
+	ffmpegCalls int
+}
+
+var _ proc.Runner = (*fakeTools)(nil)
+
+func (f *fakeTools) Run(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+	switch name {
+	case "fake-yt-dlp":
+		return f.runYTDLP(args, stdout, stderr)
+	case "fake-ffmpeg":
+		return f.runFFMPEG(args, stdout, stderr)
+	}
+	return fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+}
+
+func (f *fakeTools) runYTDLP(args []string, stdout, stderr io.Writer) error {
+	switch {
+	case slices.Contains(args, "--version"):
+		fmt.Fprintln(stdout, "2025.01.01")
+	case slices.Contains(args, "--dump-json"):
+		fmt.Fprintln(stdout, testVideoJSON)
+	case slices.Contains(args, "--dump-single-json"):
+		fmt.Fprintln(stdout, testVideoJSON)
+	case slices.Contains(args, "--skip-download"):
+		dir := filepath.Dir(args[slices.Index(args, "--output")+1])
+		for name, content := range f.subtitles {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				return err
+			}
+		}
+	case slices.Contains(args, "--extract-audio"):
+		if f.downloadErr != nil {
+			fmt.Fprintln(stderr, "ERROR: unable to download video data")
+			return f.downloadErr
+		}
+		audioPath := strings.Replace(args[slices.Index(args, "--output")+1], "%(id)s.%(ext)s", "abc123.m4a", 1)
+		if err := os.WriteFile(audioPath, []byte("audio"), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintln(stderr, "yt-transcribe-progress 5 10 NA 1024 1")
+		fmt.Fprintln(stdout, testVideoJSON)
+		fmt.Fprintln(stdout, audioPath)
+	default:
+		f.t.Errorf("unexpected yt-dlp call: %v", args)
+		return errors.New("exit status 2")
+	}
+	return nil
+}
+
+func (f *fakeTools) runFFMPEG(args []string, stdout, stderr io.Writer) error {
+	if slices.Contains(args, "-version") {
+		fmt.Fprintln(stdout, "ffmpeg version 8.0")
+		return nil
+	}
+	f.ffmpegCalls++
+
+	input := args[slices.Index(args, "-i")+1]
+	if _, err := os.Stat(input); err != nil {
+		return fmt.Errorf("input not found: %w", err)
+	}
+	m := testDestinationPattern.FindStringSubmatch(args[slices.Index(args, "-af")+1])
+	if m == nil {
+		return errors.New("no whisper destination")
+	}
+	fmt.Fprintln(stderr, "  Duration: 00:00:05.00, start: 0.000000, bitrate: 128 kb/s")
+	fmt.Fprint(stderr, "out_time_us=5000000\nspeed=10x\nprogress=end\n")
+	return os.WriteFile(m[1], []byte("1\n00:00:00,000 --> 00:00:05,000\nHello from Whisper\n"), 0o644)
+}
+
+// blockingTools is a fakeTools whose transcriptions block until released or cancelled.
+// It records how many transcriptions run at the same time.
+type blockingTools struct {
+	*fakeTools
+	release chan struct{} // Closed to let the transcriptions finish
+
+	mu        sync.Mutex
+	started   []string // Inputs of the started transcriptions
+	active    int
+	maxActive int
+}
+
+func newBlockingTools(t *testing.T) *blockingTools {
+	return &blockingTools{fakeTools: &fakeTools{t: t}, release: make(chan struct{})}
+}
+
+func (b *blockingTools) Run(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+	if name != "fake-ffmpeg" || !slices.Contains(args, "-af") {
+		return b.fakeTools.Run(ctx, name, args, stdout, stderr)
+	}
+
+	b.mu.Lock()
+	b.started = append(b.started, args[slices.Index(args, "-i")+1])
+	b.active++
+	b.maxActive = max(b.maxActive, b.active)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.active--
+		b.mu.Unlock()
+	}()
+
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fakeTools.Run(ctx, name, args, stdout, stderr)
+}
+
+// waitActive waits until n transcriptions are running.
+func (b *blockingTools) waitActive(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		active := b.active
+		b.mu.Unlock()
+		if active == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%d transcriptions did not start", n)
+}
+
+// runWorker starts a worker using tools and stops it when the test ends.
+func runWorker(t *testing.T, tools *fakeTools, captionPolicy transcript.CaptionPolicy) {
+	t.Helper()
+	runWorkerConfig(t, TranscriptionWorkerConfig{
+		WhisperModelPath: "model.bin",
+		YTDLPPath:        "fake-yt-dlp",
+		FFMPEGPath:       "fake-ffmpeg",
+		Runner:           tools,
+		CaptionPolicy:    captionPolicy,
+	})
+}
+
+// runWorkerConfig starts a worker with the given configuration on an empty queue and stops it when the test ends.
+func runWorkerConfig(t *testing.T, cfg TranscriptionWorkerConfig) {
+	t.Helper()
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+	startWorker(t, cfg)
+}
+
+// startWorker starts a worker on the current queue and stops it when the test ends.
+func startWorker(t *testing.T, cfg TranscriptionWorkerConfig) {
+	t.Helper()
+	worker, err := NewTranscriptionWorker(cfg)
+	if err != nil {
+		t.Fatalf("NewTranscriptionWorker: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.RunTranscriptionWorker(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitFinished waits until the job is no longer in progress and returns it.
+func waitFinished(t *testing.T, id string) *queue.VideoInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		videoInfo := queue.Get(id)
+		if videoInfo != nil && videoInfo.Status != queue.VideoStatusPending && !videoInfo.Status.IsInProgress() {
+			return videoInfo
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish, status: %s", id, queue.Get(id).Status)
+	return nil
+}
+
+// fakeLLM is an OpenAI-compatible chat completions endpoint answering with the first line of the user prompt.
+func fakeLLM(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Stream   bool `json:"stream"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prompt := request.Messages[len(request.Messages)-1].Content
+		firstLine, _, _ := strings.Cut(prompt, "\n")
+
+		if request.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			data, _ := json.Marshal(map[string]any{
+				"id":      "chatcmpl-test",
+				"object":  "chat.completion.chunk",
+				"model":   "test",
+				"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": firstLine}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"model":   "test",
+			"choices": []map[string]any{{"index": 0, "finish_reason": "stop", "message": map[string]any{"role": "assistant", "content": firstLine}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// addTestUpload adds a job for an uploaded file, which skips yt-dlp.
+func addTestUpload(t *testing.T, videoID string, summaryStyle string) string {
+	t.Helper()
+	sourceFile := filepath.Join(t.TempDir(), "source.m4a")
+	if err := os.WriteFile(sourceFile, []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	videoInfo, err := queue.Add(queue.NewVideoInfo{
+		Extractor:    uploadExtractor,
+		VideoID:      videoID,
+		Title:        "Uploaded " + videoID,
+		SourceFile:   sourceFile,
+		SummaryStyle: summaryStyle,
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return videoInfo.ID
+}
+
+func addTestVideo(t *testing.T) string {
+	t.Helper()
+	videoInfo, err := queue.Add(queue.NewVideoInfo{
+		VideoURL:  "https://www.youtube.com/watch?v=abc123",
+		Extractor: "youtube",
+		VideoID:   "abc123",
+		Title:     "Listed title",
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return videoInfo.ID
+}
+
+func historyStatuses(videoInfo *queue.VideoInfo) []queue.VideoStatus {
+	var statuses []queue.VideoStatus
+	for _, change := range videoInfo.History {
+		statuses = append(statuses, change.Status)
+	}
+	return statuses
+}
+
+func TestWorkerPipeline(t *testing.T) {
+	tools := &fakeTools{t: t}
+	runWorker(t, tools, transcript.CaptionPolicyWhisper)
+
+	videoInfo := waitFinished(t, addTestVideo(t))
+	if videoInfo.Status != queue.VideoStatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", videoInfo.Status, videoInfo.Error)
+	}
+
+	wantStatuses := []queue.VideoStatus{
+		queue.VideoStatusPending,
+		queue.VideoStatusProcessing,
+		queue.VideoStatusFetchingMetadata,
+		queue.VideoStatusDownloading,
+		queue.VideoStatusTranscribing,
+		queue.VideoStatusSummarizing,
+		queue.VideoStatusCompleted,
+	}
+	if got := historyStatuses(videoInfo); !slices.Equal(got, wantStatuses) {
+		t.Errorf("history = %v, want %v", got, wantStatuses)
+	}
+
+	if videoInfo.Title != "Full title" || videoInfo.Details.Channel != "Channel" || videoInfo.Details.Description != "About the video" {
+		t.Errorf("metadata was not stored: %+v", videoInfo)
+	}
+	want := []transcript.Segment{{Start: 0, End: 5 * time.Second, Text: "Hello from Whisper"}}
+	if !slices.Equal(videoInfo.Transcript.Segments, want) || videoInfo.Transcript.Source != transcript.SourceWhisper {
+		t.Errorf("transcript = %+v, want %+v from Whisper", videoInfo.Transcript, want)
+	}
+	if videoInfo.Progress != nil {
+		t.Errorf("progress = %+v, want it cleared after completion", videoInfo.Progress)
+	}
+	if videoInfo.WorkDir != "" {
+		t.Errorf("work dir %q was not cleared", videoInfo.WorkDir)
+	}
+	if tools.ffmpegCalls != 1 {
+		t.Errorf("ffmpeg transcribed %d times, want 1", tools.ffmpegCalls)
+	}
+}
+
+func TestWorkerPipelineDownloadFailure(t *testing.T) {
+	tools := &fakeTools{t: t, downloadErr: errors.New("exit status 1")}
+	runWorker(t, tools, transcript.CaptionPolicyWhisper)
+
+	videoInfo := waitFinished(t, addTestVideo(t))
+	if videoInfo.Status != queue.VideoStatusDownloadFailed {
+		t.Fatalf("status = %s, want %s", videoInfo.Status, queue.VideoStatusDownloadFailed)
+	}
+	if !strings.Contains(videoInfo.Error, "unable to download video data") {
+		t.Errorf("error %q does not contain the yt-dlp output", videoInfo.Error)
+	}
+	if tools.ffmpegCalls != 0 {
+		t.Errorf("ffmpeg ran %d times after a failed download", tools.ffmpegCalls)
+	}
+}
+
+func TestWorkerPipelineCaptions(t *testing.T) {
+	tools := &fakeTools{t: t, subtitles: map[string]string{
+		"abc123.en.vtt": "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nFrom captions\n",
+	}}
+	runWorker(t, tools, transcript.CaptionPolicyManual)
+
+	videoInfo := waitFinished(t, addTestVideo(t))
+	if videoInfo.Status != queue.VideoStatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", videoInfo.Status, videoInfo.Error)
+	}
+	if videoInfo.Transcript.Source != transcript.SourceManualCaptions || len(videoInfo.Transcript.Segments) != 1 {
+		t.Errorf("transcript = %+v, want the captions", videoInfo.Transcript)
+	}
+	if tools.ffmpegCalls != 0 {
+		t.Errorf("ffmpeg ran %d times although captions were available", tools.ffmpegCalls)
+	}
+}
+
+func TestRecoverInterruptedJobsRemovesWorkDirs(t *testing.T) {
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+	id := addTestVideo(t)
+	queue.GetNext()
+	if err := queue.Transition(id, queue.VideoStatusDownloading, ""); err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "abc123.m4a.part"), []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	queue.SetWorkDir(id, workDir)
+
+	worker, err := NewTranscriptionWorker(TranscriptionWorkerConfig{
+		WhisperModelPath: "model.bin",
+		Runner:           &fakeTools{t: t},
+		RecoveryPolicy:   queue.RecoveryPolicyRequeue,
+	})
+	if err != nil {
+		t.Fatalf("NewTranscriptionWorker: %v", err)
+	}
+	worker.RecoverInterruptedJobs()
+
+	if _, err := os.Stat(workDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("work dir of the interrupted job still exists: %v", err)
+	}
+	if videoInfo := queue.Get(id); videoInfo.Status != queue.VideoStatusPending || videoInfo.WorkDir != "" {
+		t.Errorf("video = %+v, want it requeued without a work dir", videoInfo)
+	}
+}
+
+func TestWorkerSummaryStyle(t *testing.T) {
+	runWorkerConfig(t, TranscriptionWorkerConfig{
+		LLMEndpoint:      fakeLLM(t),
+		LLMModel:         "test",
+		SummaryStyle:     "bullets",
+		WhisperModelPath: "model.bin",
+		FFMPEGPath:       "fake-ffmpeg",
+		Runner:           &fakeTools{t: t},
+	})
+
+	tests := []struct {
+		style      string
+		wantStyle  string
+		wantPrefix string
+	}{
+		{style: "tldr", wantStyle: "tldr", wantPrefix: "Write a TL;DR"},
+		{style: "", wantStyle: "bullets", wantPrefix: "Write a digest"},
+	}
+	for i, tt := range tests {
+		videoInfo := waitFinished(t, addTestUpload(t, fmt.Sprint(i), tt.style))
+		if videoInfo.Status != queue.VideoStatusCompleted {
+			t.Fatalf("status = %s (%s), want completed", videoInfo.Status, videoInfo.Error)
+		}
+		if len(videoInfo.Summaries) != 1 {
+			t.Fatalf("got %d summaries, want 1", len(videoInfo.Summaries))
+		}
+		summary := videoInfo.Summaries[0]
+		if summary.Style != tt.wantStyle || !strings.HasPrefix(summary.Text, tt.wantPrefix) {
+			t.Errorf("summary %q in style %q, want the %s style", summary.Text, summary.Style, tt.wantStyle)
+		}
+		if summary.Model != "test" || summary.PromptHash == "" || summary.CreatedAt.IsZero() {
+			t.Errorf("summary = %+v, want the model, prompt hash and creation time recorded", summary)
+		}
+	}
+}
+
+func TestWorkerAdditionalSummary(t *testing.T) {
+	tools := &fakeTools{t: t}
+	runWorkerConfig(t, TranscriptionWorkerConfig{
+		LLMEndpoint:      fakeLLM(t),
+		LLMModel:         "test",
+		WhisperModelPath: "model.bin",
+		FFMPEGPath:       "fake-ffmpeg",
+		Runner:           tools,
+	})
+
+	id := addTestUpload(t, "1", "")
+	waitFinished(t, id)
+	if err := queue.RequestSummary(id, "minutes", "other-model"); err != nil {
+		t.Fatalf("RequestSummary: %v", err)
+	}
+	videoInfo := waitFinished(t, id)
+	if videoInfo.Status != queue.VideoStatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", videoInfo.Status, videoInfo.Error)
+	}
+
+	var got []string
+	for _, summary := range videoInfo.Summaries {
+		got = append(got, summary.Style+"/"+summary.Model)
+	}
+	if want := []string{"default/test", "minutes/other-model"}; !slices.Equal(got, want) {
+		t.Errorf("summaries = %v, want %v", got, want)
+	}
+	if tools.ffmpegCalls != 1 {
+		t.Errorf("ffmpeg transcribed %d times, want the stored transcript to be reused", tools.ffmpegCalls)
+	}
+}
+
+func TestWorkerRecoveredSummaryKeepsEarlierResults(t *testing.T) {
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+
+	// A finished job waiting for a summary slot for another summary when the server stopped
+	id := addTestUpload(t, "1", "")
+	queue.GetNext()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(queue.Transition(id, queue.VideoStatusTranscribing, ""))
+	must(queue.SetTranscript(id, transcript.FromText("Hello from Whisper")))
+	must(queue.Transition(id, queue.VideoStatusSummarizing, ""))
+	must(queue.AddSummary(id, queue.Summary{Text: "Earlier", Style: "default", Model: "test"}))
+	must(queue.Transition(id, queue.VideoStatusCompleted, ""))
+	must(queue.AddExchange(id, queue.Exchange{Question: "Who?", Answer: "Whisper"}))
+	must(queue.RequestSummary(id, "minutes", "other-model"))
+	queue.GetNext()
+
+	tools := &fakeTools{t: t}
+	startWorker(t, TranscriptionWorkerConfig{
+		LLMEndpoint:      fakeLLM(t),
+		LLMModel:         "test",
+		WhisperModelPath: "model.bin",
+		FFMPEGPath:       "fake-ffmpeg",
+		Runner:           tools,
+		RecoveryPolicy:   queue.RecoveryPolicyRequeue,
+	})
+
+	videoInfo := waitFinished(t, id)
+	if videoInfo.Status != queue.VideoStatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", videoInfo.Status, videoInfo.Error)
+	}
+	var got []string
+	for _, summary := range videoInfo.Summaries {
+		got = append(got, summary.Style+"/"+summary.Model)
+	}
+	if want := []string{"default/test", "minutes/other-model"}; !slices.Equal(got, want) {
+		t.Errorf("summaries = %v, want %v", got, want)
+	}
+	if len(videoInfo.Conversation) != 1 {
+		t.Errorf("conversation = %+v, want the earlier question kept", videoInfo.Conversation)
+	}
+	if tools.ffmpegCalls != 0 {
+		t.Errorf("ffmpeg transcribed %d times, want the stored transcript to be reused", tools.ffmpegCalls)
+	}
+}
+
+func TestWorkerStopsOnCancel(t *testing.T) {
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+	tools := newBlockingTools(t)
+	worker, err := NewTranscriptionWorker(TranscriptionWorkerConfig{
+		WhisperModelPath: "model.bin",
+		FFMPEGPath:       "fake-ffmpeg",
+		Runner:           tools,
+		Workers:          3,
+	})
+	if err != nil {
+		t.Fatalf("NewTranscriptionWorker: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.RunTranscriptionWorker(ctx)
+		close(done)
+	}()
+
+	// One worker is busy transcribing, the others wait for jobs
+	id := addTestUpload(t, "1", "")
+	tools.waitActive(t, 1)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker pool did not stop after its context was cancelled")
+	}
+	// The interrupted job is left for recovery on the next start
+	if videoInfo := queue.Get(id); videoInfo.Status != queue.VideoStatusTranscribing {
+		t.Errorf("status = %s, want the job left transcribing", videoInfo.Status)
+	}
+}
+
+func TestWorkerStageLimits(t *testing.T) {
+	tools := newBlockingTools(t)
+	runWorkerConfig(t, TranscriptionWorkerConfig{
+		WhisperModelPath: "model.bin",
+		FFMPEGPath:       "fake-ffmpeg",
+		Runner:           tools,
+		Workers:          4,
+		StageLimits:      StageLimits{Transcriptions: 2},
+	})
+
+	var ids []string
+	for i := range 6 {
+		ids = append(ids, addTestUpload(t, fmt.Sprint(i), ""))
+	}
+	tools.waitActive(t, 2)
+	// Give the other workers time to start a transcription they should not
+	time.Sleep(50 * time.Millisecond)
+	close(tools.release)
+
+	for _, id := range ids {
+		videoInfo := waitFinished(t, id)
+		if videoInfo.Status != queue.VideoStatusCompleted {
+			t.Errorf("%s status = %s (%s), want completed", id, videoInfo.Status, videoInfo.Error)
+		}
+		// Each job is taken from the queue once
+		taken := 0
+		for _, status := range historyStatuses(videoInfo) {
+			if status == queue.VideoStatusProcessing {
+				taken++
+			}
+		}
+		if taken != 1 {
+			t.Errorf("%s was taken from the queue %d times, want once", id, taken)
+		}
+	}
+
+	tools.mu.Lock()
+	defer tools.mu.Unlock()
+	if tools.maxActive != 2 {
+		t.Errorf("%d transcriptions ran at the same time, want the limit of 2", tools.maxActive)
+	}
+	started := slices.Clone(tools.started)
+	slices.Sort(started)
+	if len(started) != len(ids) || len(slices.Compact(started)) != len(ids) {
+		t.Errorf("transcribed %v, want each of the %d jobs once", tools.started, len(ids))
+	}
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// charsPerToken approximates how many characters make up a token for common tokenizers.
+// It errs on the side of overestimating tokens for English text.
+const charsPerToken = 4
+
+// estimateTokens approximates the number of tokens of text without model-specific tokenizers.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// chunk is a consecutive part of a transcript that fits into a single prompt.
+type chunk struct {
+	Start    time.Duration
+	End      time.Duration
+	Segments []transcript.Segment
+}
+
+// Text returns the transcript text of the chunk.
+func (c chunk) Text() string {
+	return transcript.Transcript{Segments: c.Segments}.Text()
+}
+
+// segmentTokens estimates the tokens of a segment as it appears in the text of a chunk.
+func segmentTokens(s transcript.Segment) int {
+	return estimateTokens(s.Text) + 1
+}
+
+// chunkTranscript splits a transcript into chunks of at most maxTokens, breaking only between segments.
+// A chunk is ended early at a chapter boundary once it is at least half full, so chunks follow the
+// structure of the video. Each chunk after the first repeats up to overlapTokens of the end of the
+// previous one for context. A single segment longer than maxTokens forms a chunk of its own.
+func chunkTranscript(t transcript.Transcript, chapters []transcript.Chapter, maxTokens, overlapTokens int) []chunk {
+	var chunks []chunk
+	var current []transcript.Segment
+	currentTokens := 0
+	// Segments at the start of current that repeat the previous chunk
+	overlap := 0
+
+	flush := func() {
+		if len(current) <= overlap {
+			return
+		}
+		chunks = append(chunks, chunk{
+			Start:    current[0].Start,
+			End:      current[len(current)-1].End,
+			Segments: current,
+		})
+
+		// Start the next chunk with the tail of this one
+		var tail []transcript.Segment
+		tailTokens := 0
+		for i := len(current) - 1; i > 0; i-- {
+			tokens := segmentTokens(current[i])
+			if tailTokens+tokens > overlapTokens {
+				break
+			}
+			tail = append([]transcript.Segment{current[i]}, tail...)
+			tailTokens += tokens
+		}
+		current = tail
+		currentTokens = tailTokens
+		overlap = len(tail)
+	}
+
+	for _, segment := range t.Segments {
+		tokens := segmentTokens(segment)
+		full := currentTokens+tokens > maxTokens
+		atChapter := currentTokens >= maxTokens/2 && startsChapter(chapters, segment)
+		if len(current) > overlap && (full || atChapter) {
+			flush()
+		}
+		// Drop the overlap if the segment would not fit next to it
+		if currentTokens+tokens > maxTokens {
+			current, currentTokens, overlap = nil, 0, 0
+		}
+		current = append(current, segment)
+		currentTokens += tokens
+	}
+	flush()
+
+	return chunks
+}
+
+// startsChapter reports whether a chapter begins within the segment.
+func startsChapter(chapters []transcript.Chapter, segment transcript.Segment) bool {
+	for _, c := range chapters {
+		if c.Start > 0 && c.Start >= segment.Start && c.Start < segment.End {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkHeading describes the position of a chunk in the video for the prompt.
+func chunkHeading(c chunk, index, total int) string {
+	return fmt.Sprintf("Part %d of %d (%s - %s)", index+1, total, transcript.FormatClock(c.Start), transcript.FormatClock(c.End))
+}
+
+// batchTexts groups texts into batches whose combined estimated tokens stay within maxTokens.
+// Every batch holds at least two texts, unless only one is left, so repeated batching always shrinks the input.
+func batchTexts(texts []string, maxTokens int) [][]string {
+	var batches [][]string
+	var current []string
+	currentTokens := 0
+	for _, text := range texts {
+		tokens := estimateTokens(text)
+		if len(current) >= 2 && currentTokens+tokens > maxTokens {
+			batches = append(batches, current)
+			current, currentTokens = nil, 0
+		}
+		current = append(current, text)
+		currentTokens += tokens
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// joinSummaries joins partial summaries with separators for a reduce prompt.
+func joinSummaries(summaries []string) string {
+	return strings.Join(summaries, "\n\n---\n\n")
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// testTranscript returns n segments of 10 seconds, each with 7 characters of text (3 estimated tokens).
+func testTranscript(n int) transcript.Transcript {
+	var t transcript.Transcript
+	for i := range n {
+		t.Segments = append(t.Segments, transcript.Segment{
+			Start: time.Duration(i) * 10 * time.Second,
+			End:   time.Duration(i+1) * 10 * time.Second,
+			Text:  "seg " + string(rune('a'+i)) + "..",
+		})
+	}
+	return t
+}
+
+// segmentIndexes returns the indexes of the chunk's segments in the test transcript.
+func segmentIndexes(c chunk) []int {
+	var indexes []int
+	for _, s := range c.Segments {
+		indexes = append(indexes, int(s.Start/(10*time.Second)))
+	}
+	return indexes
+}
+
+func TestChunkTranscript(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments int
+		chapters []transcript.Chapter
+		max      int
+		overlap  int
+		want     [][]int
+	}{
+		{
+			name:     "fits into one chunk",
+			segments: 3,
+			max:      100,
+			want:     [][]int{{0, 1, 2}},
+		},
+		{
+			name:     "split without overlap",
+			segments: 5,
+			max:      6,
+			want:     [][]int{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			name:     "split with overlap",
+			segments: 5,
+			max:      9,
+			overlap:  3,
+			want:     [][]int{{0, 1, 2}, {2, 3, 4}},
+		},
+		{
+			name:     "break at chapter once half full",
+			segments: 6,
+			chapters: []transcript.Chapter{{Title: "Intro"}, {Title: "Early", Start: 10 * time.Second}, {Title: "Main", Start: 20 * time.Second}},
+			max:      12,
+			want:     [][]int{{0, 1}, {2, 3, 4, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkTranscript(testTranscript(tt.segments), tt.chapters, tt.max, tt.overlap)
+			var got [][]int
+			for _, c := range chunks {
+				got = append(got, segmentIndexes(c))
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]int]) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkTranscriptLongSegment(t *testing.T) {
+	tr := testTranscript(3)
+	tr.Segments[1].Text = strings.Repeat("long ", 20)
+
+	chunks := chunkTranscript(tr, nil, 8, 3)
+	var got [][]int
+	for _, c := range chunks {
+		got = append(got, segmentIndexes(c))
+	}
+	if want := [][]int{{0}, {1}, {2}}; !slices.EqualFunc(got, want, slices.Equal[[]int]) {
+		t.Errorf("chunks = %v, want %v", got, want)
+	}
+	if chunks[1].Start != 10*time.Second || chunks[1].End != 20*time.Second {
+		t.Errorf("chunk spans %v - %v, want 10s - 20s", chunks[1].Start, chunks[1].End)
+	}
+}
+
+func TestBatchTexts(t *testing.T) {
+	texts := []string{strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40), strings.Repeat("d", 400)}
+
+	batches := batchTexts(texts, 25)
+	var sizes []int
+	for _, batch := range batches {
+		sizes = append(sizes, len(batch))
+	}
+	// Batches take at least two texts, even if they exceed the limit together
+	if want := []int{2, 2}; !slices.Equal(sizes, want) {
+		t.Errorf("batch sizes = %v, want %v", sizes, want)
+	}
+}
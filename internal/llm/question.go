@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+	"github.com/openai/openai-go"
+)
+
+const questionSystemPrompt = `
+You are an expert video content analyzer answering questions about a video. The user describes the video and provides its transcription, or excerpts of it, with each line starting with the time it is said at in the video, e.g. [1:02].
+Answer using only the transcription and the earlier conversation. If they do not contain the answer, say so instead of guessing.
+Cite the times of the lines your answer is based on in square brackets right after the statements they support, e.g. [1:02] or [1:02:03]. Keep answers short.`
+
+const (
+	// maxHistory is the number of earlier exchanges sent along with a question, so follow-up questions work.
+	maxHistory = 6
+	// excerptsPerQuestion is the number of chunks a question about a long transcript is answered from.
+	// Together they are about as long as a chunk of the summarizer, so the prompt fits the same model.
+	excerptsPerQuestion = 5
+)
+
+// Exchange is an earlier question about a transcript and its answer.
+type Exchange struct {
+	Question string
+	Answer   string
+}
+
+// Answer is the answer to a question about a transcript.
+type Answer struct {
+	Text  string
+	Model string
+	// Citations are the times in the video the answer refers to, in the order they are first cited.
+	Citations []time.Duration
+}
+
+// Answerer answers questions about transcripts.
+type Answerer interface {
+	AnswerQuestion(ctx context.Context, video Video, t transcript.Transcript, history []Exchange, question string) (Answer, error)
+}
+
+// AnswerQuestion answers a question about the transcript, taking the latest exchanges of the conversation into account.
+// Transcripts that fit into a chunk are sent in full. Longer ones are split into smaller chunks and only those
+// matching the question (and the previous one, for follow-up questions) best are sent, see selectChunks.
+func (s *OpenAICompatibleSummarizer) AnswerQuestion(ctx context.Context, video Video, t transcript.Transcript, history []Exchange, question string) (Answer, error) {
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+
+	heading := "Transcription:\n"
+	excerpts := []chunk{{Segments: t.Segments}}
+	if estimateTokens(t.Text()) > s.chunkTokens {
+		query := question
+		if len(history) > 0 {
+			query += "\n" + history[len(history)-1].Question
+		}
+		chunks := chunkTranscript(t, video.Chapters, s.chunkTokens/excerptsPerQuestion, s.chunkOverlap/excerptsPerQuestion)
+		excerpts = selectChunks(chunks, query, excerptsPerQuestion)
+		heading = "Transcription excerpts, \"...\" marks left out parts:\n"
+	}
+
+	messages := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(questionSystemPrompt)}
+	for _, exchange := range history {
+		messages = append(messages, openai.UserMessage(exchange.Question), openai.AssistantMessage(exchange.Answer))
+	}
+	messages = append(messages, openai.UserMessage(videoPrompt(video)+heading+excerptText(excerpts)+"\nQuestion: "+question))
+
+	text, err := s.chat(ctx, s.model, messages)
+	if err != nil {
+		return Answer{}, err
+	}
+	return Answer{
+		Text:      text,
+		Model:     s.model,
+		Citations: citations(text, t.Duration()),
+	}, nil
+}
+
+// excerptText formats transcript excerpts for a question, with each segment on a line starting with its time.
+// Segments repeated by overlapping excerpts are left out and gaps between excerpts are marked.
+func excerptText(excerpts []chunk) string {
+	var b strings.Builder
+	var end time.Duration
+	for i, c := range excerpts {
+		if i > 0 && c.Start > end {
+			b.WriteString("...\n")
+		}
+		for _, segment := range c.Segments {
+			if i > 0 && segment.End <= end {
+				continue
+			}
+			fmt.Fprintf(&b, "[%s] %s\n", transcript.FormatClock(segment.Start), strings.TrimSpace(segment.Text))
+			end = segment.End
+		}
+	}
+	return b.String()
+}
+
+var (
+	// citationPattern matches text in square brackets, e.g. "[1:02]" or "[1:02 - 1:30]"
+	citationPattern = regexp.MustCompile(`\[[^\[\]]*\]`)
+	clockPattern    = regexp.MustCompile(`\b\d{1,2}(?::\d{2}){1,2}\b`)
+)
+
+// citations returns the times cited in square brackets in an answer, in the order they are first cited.
+// Times after the end of the transcript are left out, if it is known.
+func citations(answer string, end time.Duration) []time.Duration {
+	var times []time.Duration
+	for _, citation := range citationPattern.FindAllString(answer, -1) {
+		for _, clock := range clockPattern.FindAllString(citation, -1) {
+			d, err := transcript.ParseClock(clock)
+			if err != nil || (end > 0 && d > end) || slices.Contains(times, d) {
+				continue
+			}
+			times = append(times, d)
+		}
+	}
+	return times
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAnswerer(t *testing.T, server *fakeChatServer, chunkTokens int) Answerer {
+	t.Helper()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	answerer, err := NewAnswerer(Config{Endpoint: ts.URL, Model: "test", ChunkTokens: chunkTokens})
+	if err != nil {
+		t.Fatalf("NewAnswerer: %v", err)
+	}
+	return answerer
+}
+
+func TestAnswerQuestionShortTranscript(t *testing.T) {
+	server := &fakeChatServer{respond: func(chatPrompt) string { return "It starts with a [0:20]." }}
+	answerer := newTestAnswerer(t, server, 1000)
+
+	var history []Exchange
+	for i := range 8 {
+		history = append(history, Exchange{Question: "Question " + string(rune('a'+i)), Answer: "Answer"})
+	}
+	answer, err := answerer.AnswerQuestion(context.Background(), Video{Title: "Short"}, testTranscript(5), history, "How does it start?")
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if answer.Text != "It starts with a [0:20]." || answer.Model != "test" || !slices.Equal(answer.Citations, []time.Duration{20 * time.Second}) {
+		t.Errorf("answer = %+v, want the response citing 0:20", answer)
+	}
+
+	p := server.prompts[0]
+	if p.System != questionSystemPrompt {
+		t.Errorf("system prompt = %q, want the question prompt", p.System)
+	}
+	for _, part := range []string{"Transcription:\n", "[0:00] seg a..\n", "[0:40] seg e..\n", "Question: How does it start?"} {
+		if !strings.Contains(p.User, part) {
+			t.Errorf("prompt does not contain %q:\n%s", part, p.User)
+		}
+	}
+	// The system prompt, the latest exchanges and the question
+	if len(p.Roles) != 1+2*maxHistory+1 || p.Roles[1] != "user" || p.Roles[2] != "assistant" {
+		t.Errorf("message roles = %v, want the last %d exchanges before the question", p.Roles, maxHistory)
+	}
+}
+
+func TestAnswerQuestionRetrieval(t *testing.T) {
+	server := &fakeChatServer{respond: func(chatPrompt) string { return "When blocked [2:10], not [1:00:00] or [9:99]." }}
+	// Each segment of 3 tokens becomes a chunk of its own
+	answerer := newTestAnswerer(t, server, 12)
+
+	tr := testTranscript(20)
+	tr.Segments[13].Text = "goroutines leak"
+	tr.Segments[17].Text = "channels block"
+	history := []Exchange{{Question: "What about channels?", Answer: "They block [2:50]."}}
+
+	answer, err := answerer.AnswerQuestion(context.Background(), Video{Title: "Long"}, tr, history, "Why do goroutines leak?")
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if !slices.Equal(answer.Citations, []time.Duration{130 * time.Second}) {
+		t.Errorf("Citations = %v, want only the time within the transcript", answer.Citations)
+	}
+
+	prompt := server.prompts[0].User
+	for _, part := range []string{"Transcription excerpts", "[2:10] goroutines leak\n", "[2:50] channels block\n", "...\n"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("prompt does not contain %q:\n%s", part, prompt)
+		}
+	}
+	if strings.Contains(prompt, "seg t..") {
+		t.Errorf("prompt contains an unrelated part of the transcript:\n%s", prompt)
+	}
+}
+
+func TestNewAnswererRequiresEndpoint(t *testing.T) {
+	if _, err := NewAnswerer(Config{Model: "test"}); err == nil {
+		t.Error("NewAnswerer without an endpoint succeeded")
+	}
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// bm25K1 limits how much repeating a word raises the score of a chunk, see rankChunks.
+const bm25K1 = 1.2
+
+// stopWords are common English words that say nothing about which part of a transcript a question is about.
+var stopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "can": true, "did": true, "do": true, "does": true, "for": true, "from": true,
+	"has": true, "have": true, "he": true, "her": true, "his": true, "how": true, "i": true, "in": true,
+	"is": true, "it": true, "its": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"she": true, "so": true, "that": true, "the": true, "their": true, "them": true, "there": true,
+	"they": true, "this": true, "to": true, "was": true, "we": true, "were": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true, "will": true, "with": true,
+	"would": true, "you": true, "your": true,
+}
+
+// terms splits text into lowercase words for matching, without stop words.
+func terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return slices.DeleteFunc(words, func(word string) bool { return stopWords[word] })
+}
+
+// rankChunks returns the indices of the chunks ordered by how well they match the query, best first.
+// Chunks are scored like BM25 without length normalization, since chunks are of similar size: every query
+// word found in a chunk adds its inverse document frequency, with diminishing returns for repetitions.
+// Chunks with the same score keep their order.
+func rankChunks(chunks []chunk, query string) (ranked []int, matched bool) {
+	queryTerms := slices.Compact(slices.Sorted(slices.Values(terms(query))))
+
+	counts := make([]map[string]int, len(chunks))
+	documentFrequency := make(map[string]int)
+	for i, c := range chunks {
+		counts[i] = make(map[string]int)
+		for _, term := range terms(c.Text()) {
+			counts[i][term]++
+		}
+		for _, term := range queryTerms {
+			if counts[i][term] > 0 {
+				documentFrequency[term]++
+			}
+		}
+	}
+
+	n := float64(len(chunks))
+	scores := make([]float64, len(chunks))
+	for i := range chunks {
+		for _, term := range queryTerms {
+			tf := float64(counts[i][term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(documentFrequency[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1)
+			matched = true
+		}
+	}
+
+	ranked = make([]int, len(chunks))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	slices.SortStableFunc(ranked, func(a, b int) int {
+		switch {
+		case scores[a] > scores[b]:
+			return -1
+		case scores[a] < scores[b]:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return ranked, matched
+}
+
+// selectChunks returns the n chunks matching the query best in the order of the transcript.
+// If no chunk matches, e.g. for "What is this about?", chunks spread evenly over the transcript are returned.
+func selectChunks(chunks []chunk, query string, n int) []chunk {
+	if len(chunks) <= n {
+		return chunks
+	}
+
+	ranked, matched := rankChunks(chunks, query)
+	indices := ranked[:n]
+	if !matched {
+		indices = make([]int, n)
+		for i := range indices {
+			indices[i] = i * len(chunks) / n
+		}
+	}
+	slices.Sort(indices)
+
+	selected := make([]chunk, 0, n)
+	for _, i := range indices {
+		selected = append(selected, chunks[i])
+	}
+	return selected
+}
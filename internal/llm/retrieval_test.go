@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// segmentChunks returns a chunk for every segment of the transcript.
+func segmentChunks(n int) []chunk {
+	var chunks []chunk
+	for _, s := range testTranscript(n).Segments {
+		chunks = append(chunks, chunk{Start: s.Start, End: s.End, Segments: []transcript.Segment{s}})
+	}
+	return chunks
+}
+
+func chunkIndexes(chunks []chunk) []int {
+	var indexes []int
+	for _, c := range chunks {
+		indexes = append(indexes, segmentIndexes(c)[0])
+	}
+	return indexes
+}
+
+func TestSelectChunks(t *testing.T) {
+	chunks := segmentChunks(10)
+	chunks[2].Segments[0].Text = "the scheduler preempts goroutines"
+	chunks[7].Segments[0].Text = "goroutines goroutines"
+	chunks[8].Segments[0].Text = "the end"
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"rare words rank higher", "How does the scheduler handle goroutines?", []int{2, 7}},
+		{"stop words do not match", "What is the end of it?", []int{8, 0}},
+		{"spread without matches", "Summarize this", []int{0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkIndexes(selectChunks(chunks, tt.query, 2))
+			if !slices.Equal(got, slices.Sorted(slices.Values(tt.want))) {
+				t.Errorf("selectChunks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCitations(t *testing.T) {
+	tests := []struct {
+		answer string
+		end    time.Duration
+		want   []time.Duration
+	}{
+		{"At [1:02] and again [1:02].", 0, []time.Duration{62 * time.Second}},
+		{"From [1:02 - 1:30] and [1:00:05]", 2 * time.Hour, []time.Duration{62 * time.Second, 90 * time.Second, time.Hour + 5*time.Second}},
+		{"Past the end [5:00]", time.Minute, nil},
+		{"Not cited: 1:02, [note], [1:99]", 0, nil},
+	}
+	for _, tt := range tests {
+		if got := citations(tt.answer, tt.end); !slices.Equal(got, tt.want) {
+			t.Errorf("citations(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+	}
+}
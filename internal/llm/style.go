@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// DefaultStyle is the name of the built-in style used when none is selected.
+const DefaultStyle = "default"
+
+// styleExt is the file extension of prompt templates.
+const styleExt = ".tmpl"
+
+//go:embed styles/*.tmpl
+var builtinStyles embed.FS
+
+// videoTemplate describes the video and its transcription. Style templates include it with {{template "video" .}}.
+const videoTemplate = `Video Title: {{.Title}}
+{{with .Channel}}Channel: {{.}}
+{{end}}{{with .Chapters}}Chapters:
+{{range .}}{{clock .Start}} {{.Title}}
+{{end}}{{end}}{{if .Partial}}Summaries of consecutive parts of the transcription:{{else}}Transcription:{{end}}
+{{.Transcript}}`
+
+// PromptData is passed to style templates.
+type PromptData struct {
+	Title       string
+	Channel     string
+	Description string
+	Duration    string
+	UploadDate  string // YYYYMMDD
+	URL         string
+	Tags        []string
+	Chapters    []transcript.Chapter
+	// Transcript is the transcription text, or the summaries of its parts for long transcripts
+	Transcript string
+	// Partial reports whether Transcript holds summaries of consecutive parts
+	Partial bool
+}
+
+// Styles holds the named prompt templates summaries can be written with.
+type Styles struct {
+	styles map[string]style
+}
+
+type style struct {
+	tmpl   *template.Template
+	source string
+}
+
+// LoadStyles loads the built-in styles and the *.tmpl files in dir, which is optional.
+// A style is named after its file, so a file in dir replaces the built-in style of the same name.
+func LoadStyles(dir string) (*Styles, error) {
+	base, err := template.New("video").Funcs(template.FuncMap{
+		"clock": transcript.FormatClock,
+	}).Parse(videoTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Styles{styles: make(map[string]style)}
+	if err := s.loadDir(base, builtinStyles, "styles"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := s.loadDir(base, os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// loadDir parses the templates in dir of fsys on top of base.
+func (s *Styles) loadDir(base *template.Template, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt templates: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != styleExt {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), styleExt)
+		text, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read prompt template %s: %w", name, err)
+		}
+
+		tmpl, err := base.Clone()
+		if err == nil {
+			tmpl, err = tmpl.New(name).Parse(string(text))
+		}
+		// Catch references to unknown variables now rather than when summarizing
+		if err == nil {
+			err = tmpl.Execute(io.Discard, PromptData{})
+		}
+		if err != nil {
+			return fmt.Errorf("invalid prompt template %s: %w", name, err)
+		}
+		s.styles[name] = style{tmpl: tmpl, source: string(text)}
+	}
+	return nil
+}
+
+// Names returns the names of all styles in alphabetical order.
+func (s *Styles) Names() []string {
+	return slices.Sorted(maps.Keys(s.styles))
+}
+
+// Validate returns an error if there is no style with the given name.
+func (s *Styles) Validate(name string) error {
+	if _, ok := s.styles[name]; !ok {
+		return fmt.Errorf("unknown summary style %q (available: %s)", name, strings.Join(s.Names(), ", "))
+	}
+	return nil
+}
+
+// Prompt renders the style with the given data.
+func (s *Styles) Prompt(name string, data PromptData) (string, error) {
+	if err := s.Validate(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := s.styles[name].tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render summary style %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// Source returns the template text of the style, or an empty string if there is no such style.
+func (s *Styles) Source(name string) string {
+	return s.styles[name].source
+}
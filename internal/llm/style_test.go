@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+func writeStyle(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadStylesBuiltin(t *testing.T) {
+	styles, err := LoadStyles("")
+	if err != nil {
+		t.Fatalf("LoadStyles: %v", err)
+	}
+	want := []string{"bullets", "default", "minutes", "study-notes", "tldr"}
+	if got := styles.Names(); !slices.Equal(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	prompt, err := styles.Prompt(DefaultStyle, PromptData{
+		Title:      "Go tour",
+		Channel:    "Gophers",
+		Chapters:   []transcript.Chapter{{Title: "Intro"}, {Title: "Channels", Start: 90 * time.Second}},
+		Transcript: "Hello there",
+	})
+	if err != nil {
+		t.Fatalf("Prompt: %v", err)
+	}
+	for _, part := range []string{"Video Title: Go tour\n", "Channel: Gophers\n", "1:30 Channels\n", "Transcription:\nHello there"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("prompt does not contain %q:\n%s", part, prompt)
+		}
+	}
+}
+
+func TestLoadStylesFromDir(t *testing.T) {
+	dir := t.TempDir()
+	writeStyle(t, dir, "tldr.tmpl", "One line about {{.Title}}: {{.Transcript}}")
+	writeStyle(t, dir, "haiku.tmpl", "Write a haiku.\n{{template \"video\" .}}")
+	writeStyle(t, dir, "notes.txt", "not a template")
+
+	styles, err := LoadStyles(dir)
+	if err != nil {
+		t.Fatalf("LoadStyles: %v", err)
+	}
+	if err := styles.Validate("haiku"); err != nil {
+		t.Errorf("user-defined style not loaded: %v", err)
+	}
+	if err := styles.Validate("notes"); err == nil {
+		t.Error("file without the .tmpl extension was loaded as a style")
+	}
+
+	prompt, err := styles.Prompt("tldr", PromptData{Title: "Go tour", Transcript: "Hello"})
+	if err != nil || prompt != "One line about Go tour: Hello" {
+		t.Errorf("Prompt = %q, %v, want the user-defined template to replace the built-in one", prompt, err)
+	}
+}
+
+func TestLoadStylesInvalid(t *testing.T) {
+	for name, text := range map[string]string{
+		"syntax":           "{{.Title",
+		"unknown variable": "{{.Speaker}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeStyle(t, dir, "broken.tmpl", text)
+			if _, err := LoadStyles(dir); err == nil || !strings.Contains(err.Error(), "broken") {
+				t.Errorf("LoadStyles error = %v, want an error naming the template", err)
+			}
+		})
+	}
+}
+
+func TestSummarizeTranscriptStyle(t *testing.T) {
+	server := &fakeChatServer{}
+	server.respond = func(p chatPrompt) string { return "summary" }
+	summarizer := newTestSummarizer(t, server, 12, 0)
+
+	summary, err := summarizer.SummarizeTranscript(context.Background(), Video{Title: "Long"}, testTranscript(20), SummaryOptions{Style: "tldr"})
+	if err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	if summary.Style != "tldr" {
+		t.Errorf("Style = %q, want tldr", summary.Style)
+	}
+	final := server.prompts[len(server.prompts)-1]
+	if !strings.HasPrefix(final.User, "Write a TL;DR") {
+		t.Errorf("final prompt does not use the tldr style: %q", final.User)
+	}
+	if !strings.Contains(final.User, "Summaries of consecutive parts") {
+		t.Errorf("final prompt of a long transcript does not mark part summaries: %q", final.User)
+	}
+
+	if _, err := summarizer.SummarizeTranscript(context.Background(), Video{}, testTranscript(1), SummaryOptions{Style: "missing"}); err == nil {
+		t.Error("SummarizeTranscript with an unknown style succeeded")
+	}
+
+	other, err := summarizer.SummarizeTranscript(context.Background(), Video{}, testTranscript(1), SummaryOptions{Style: "bullets"})
+	if err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	if other.PromptHash == summary.PromptHash {
+		t.Errorf("styles tldr and bullets have the same prompt hash %q", summary.PromptHash)
+	}
+}
@@ -1,78 +1,295 @@
 package llm
 
 import (
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
 
+	"github.com/exler/yt-transcribe/internal/transcript"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
 
 const summarizerSystemPrompt = `
-You are an expert video content analyzer. When the user provides a video title and transcription, create a comprehensive summary that extracts maximum context, insights and takeaways.
-Do not use Markdown formatting, lists or bullet points. Write in a clear, engaging style suitable for a general audience.
-Prioritize accuracy over speculation, but make reasonable inferences when context strongly suggests them.`
+You are an expert video content analyzer. The user describes a video and provides its transcription, or summaries of consecutive parts of it, together with instructions for the summary.
+Follow the instructions on the form and length of the summary. Prioritize accuracy over speculation, but make reasonable inferences when context strongly suggests them.`
+
+// Video describes the video a transcript belongs to.
+type Video struct {
+	Title       string
+	Channel     string
+	Description string
+	Duration    string
+	UploadDate  string
+	URL         string
+	Tags        []string
+	Chapters    []transcript.Chapter
+}
+
+// SummaryOptions selects how a summary is written.
+type SummaryOptions struct {
+	Style string // Name of the style, see Styles. Empty for DefaultStyle
+	Model string // Empty for the model of the summarizer
+	// OnText is called with every piece of the summary as it is written, if set. Only the
+	// final request is streamed, the parts of long transcripts are summarized beforehand.
+	OnText func(text string)
+}
+
+// Summary is a summary of a transcript and how it was written.
+type Summary struct {
+	Text  string
+	Style string
+	Model string
+	// PromptHash identifies the prompts the summary was written with,
+	// so summaries written after a template was edited can be told apart.
+	PromptHash string
+}
 
 // Summarizer defines the interface for text summarization services
 type Summarizer interface {
-	SummarizeText(ctx context.Context, title, text string) (string, error)
+	SummarizeTranscript(ctx context.Context, video Video, t transcript.Transcript, opts SummaryOptions) (Summary, error)
 }
 
 // NoOpSummarizer is a disabled summarizer that returns empty summaries
 type NoOpSummarizer struct{}
 
-func (n *NoOpSummarizer) SummarizeText(ctx context.Context, title, text string) (string, error) {
-	return "", nil
+func (n *NoOpSummarizer) SummarizeTranscript(ctx context.Context, video Video, t transcript.Transcript, opts SummaryOptions) (Summary, error) {
+	return Summary{}, nil
+}
+
+// Prompts for summarizing transcripts that are too long for a single request, see SummarizeTranscript
+const (
+	chunkSystemPrompt = `
+You are an expert video content analyzer. The user provides a video title and one part of the video's transcription.
+Summarize the key points, facts, arguments and takeaways of this part only, in a few paragraphs of plain text without Markdown formatting or lists.
+Your summary will be combined with the summaries of the other parts later. The beginning of the part may repeat the end of the previous one.`
+
+	combineSystemPrompt = `
+You are an expert video content analyzer. The user provides a video title and summaries of consecutive parts of the video.
+Combine them into a single summary of these parts in plain text without Markdown formatting or lists, keeping all key points in their order.`
+)
+
+// Default chunking of transcripts, sized for local models with a context of 4096 tokens
+const (
+	DefaultChunkTokens  = 2500
+	DefaultChunkOverlap = 200
+)
+
+// Config configures a summarizer.
+type Config struct {
+	Endpoint string // Empty disables summarization
+	Token    string // Optional for Ollama, required for OpenAI
+	Model    string
+
+	// Transcripts longer than ChunkTokens (estimated) are summarized in chunks of about that size,
+	// each repeating ChunkOverlap tokens of the previous one, and the summaries of the chunks are combined.
+	// Questions about such transcripts are answered from the excerpts matching them best, about ChunkTokens in total.
+	// Zero uses DefaultChunkTokens and DefaultChunkOverlap.
+	ChunkTokens  int
+	ChunkOverlap int
+
+	// Styles the summary can be written with, nil for the built-in styles only.
+	Styles *Styles
 }
 
 // OpenAICompatibleSummarizer uses the OpenAI API (compatible with both OpenAI and Ollama)
 // AIDEV-NOTE: Ollama supports OpenAI-compatible API at /v1/chat/completions
 type OpenAICompatibleSummarizer struct {
-	client openai.Client
-	model  string
+	client       openai.Client
+	model        string
+	chunkTokens  int
+	chunkOverlap int
+	styles       *Styles
 }
 
 // NewSummarizer creates a summarizer based on the provided configuration.
 // Returns NoOpSummarizer if endpoint is empty (disabled by default).
 // Uses OpenAI-compatible API for both OpenAI and Ollama providers.
-func NewSummarizer(endpoint, token, model string) (Summarizer, error) {
-	if endpoint == "" {
+func NewSummarizer(cfg Config) (Summarizer, error) {
+	if cfg.Endpoint == "" {
 		return &NoOpSummarizer{}, nil
 	}
 
-	if model == "" {
+	summarizer, err := newOpenAICompatibleSummarizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return summarizer, nil
+}
+
+// NewAnswerer creates a question answerer based on the provided configuration.
+// Unlike NewSummarizer, it requires an endpoint.
+func NewAnswerer(cfg Config) (Answerer, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+
+	answerer, err := newOpenAICompatibleSummarizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return answerer, nil
+}
+
+func newOpenAICompatibleSummarizer(cfg Config) (*OpenAICompatibleSummarizer, error) {
+	if cfg.Model == "" {
 		return nil, errors.New("model is required")
 	}
 
+	if cfg.ChunkTokens == 0 {
+		cfg.ChunkTokens = DefaultChunkTokens
+		if cfg.ChunkOverlap == 0 {
+			cfg.ChunkOverlap = DefaultChunkOverlap
+		}
+	}
+	if cfg.ChunkTokens < 0 || cfg.ChunkOverlap < 0 {
+		return nil, errors.New("chunk size and overlap must not be negative")
+	}
+	if cfg.ChunkOverlap >= cfg.ChunkTokens/2 {
+		return nil, errors.New("chunk overlap must be less than half of the chunk size")
+	}
+
+	if cfg.Styles == nil {
+		styles, err := LoadStyles("")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Styles = styles
+	}
+
 	opts := []option.RequestOption{
-		option.WithBaseURL(endpoint),
+		option.WithBaseURL(cfg.Endpoint),
 	}
 
 	// Token is optional for Ollama but required for OpenAI
-	if token != "" {
-		opts = append(opts, option.WithAPIKey(token))
+	if cfg.Token != "" {
+		opts = append(opts, option.WithAPIKey(cfg.Token))
 	}
 
 	client := openai.NewClient(opts...)
 
 	return &OpenAICompatibleSummarizer{
-		client: client,
-		model:  model,
+		client:       client,
+		model:        cfg.Model,
+		chunkTokens:  cfg.ChunkTokens,
+		chunkOverlap: cfg.ChunkOverlap,
+		styles:       cfg.Styles,
 	}, nil
 }
 
-func (s *OpenAICompatibleSummarizer) SummarizeText(ctx context.Context, title, text string) (string, error) {
-	userPrompt := `
-		Video Title: ` + title + `
-		Transcription: ` + text + `
-	`
+// SummarizeTranscript summarizes the transcript in a single request if it fits into a chunk.
+// Longer transcripts are split into chunks on segment boundaries, preferably at chapters, which are
+// summarized one by one (map). The chunk summaries are then combined in rounds until they fit into
+// a chunk, and the final summary is written from them (reduce). Only the final request uses the style.
+func (s *OpenAICompatibleSummarizer) SummarizeTranscript(ctx context.Context, video Video, t transcript.Transcript, opts SummaryOptions) (Summary, error) {
+	summary := Summary{
+		Style: cmp.Or(opts.Style, DefaultStyle),
+		Model: cmp.Or(opts.Model, s.model),
+	}
+	if err := s.styles.Validate(summary.Style); err != nil {
+		return Summary{}, err
+	}
+	summary.PromptHash = s.promptHash(summary.Style)
+
+	text, partial, err := s.reduceTranscript(ctx, summary.Model, video, t)
+	if err != nil {
+		return Summary{}, err
+	}
+	prompt, err := s.styles.Prompt(summary.Style, PromptData{
+		Title:       video.Title,
+		Channel:     video.Channel,
+		Description: video.Description,
+		Duration:    video.Duration,
+		UploadDate:  video.UploadDate,
+		URL:         video.URL,
+		Tags:        video.Tags,
+		Chapters:    video.Chapters,
+		Transcript:  text,
+		Partial:     partial,
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(summarizerSystemPrompt),
+		openai.UserMessage(prompt),
+	}
+	if opts.OnText != nil {
+		summary.Text, err = s.stream(ctx, summary.Model, messages, opts.OnText)
+	} else {
+		summary.Text, err = s.chat(ctx, summary.Model, messages)
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+	return summary, nil
+}
+
+// reduceTranscript returns the text the final summary is written from: the transcription if it fits into
+// a chunk, otherwise the combined summaries of its chunks, in which case partial is true.
+func (s *OpenAICompatibleSummarizer) reduceTranscript(ctx context.Context, model string, video Video, t transcript.Transcript) (text string, partial bool, err error) {
+	text = t.Text()
+	if estimateTokens(text) <= s.chunkTokens {
+		return text, false, nil
+	}
+
+	chunks := chunkTranscript(t, video.Chapters, s.chunkTokens, s.chunkOverlap)
+	summaries := make([]string, 0, len(chunks))
+	for i, c := range chunks {
+		heading := chunkHeading(c, i, len(chunks))
+		summary, err := s.complete(ctx, model, chunkSystemPrompt, videoPrompt(video)+heading+"\nTranscription: "+c.Text())
+		if err != nil {
+			return "", false, fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, heading+"\n"+summary)
+	}
+
+	for len(summaries) > 1 && estimateTokens(joinSummaries(summaries)) > s.chunkTokens {
+		var combined []string
+		for _, batch := range batchTexts(summaries, s.chunkTokens) {
+			if len(batch) == 1 {
+				combined = append(combined, batch[0])
+				continue
+			}
+			summary, err := s.complete(ctx, model, combineSystemPrompt, videoPrompt(video)+"Summaries of consecutive parts:\n"+joinSummaries(batch))
+			if err != nil {
+				return "", false, fmt.Errorf("failed to combine part summaries: %w", err)
+			}
+			combined = append(combined, summary)
+		}
+		summaries = combined
+	}
+
+	return joinSummaries(summaries), true, nil
+}
+
+// promptHash returns a short hash of all prompts a summary in the style is written with.
+func (s *OpenAICompatibleSummarizer) promptHash(style string) string {
+	h := sha256.New()
+	for _, prompt := range []string{summarizerSystemPrompt, chunkSystemPrompt, combineSystemPrompt, videoTemplate, s.styles.Source(style)} {
+		io.WriteString(h, prompt)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// complete sends a single chat completion request and returns the response text.
+func (s *OpenAICompatibleSummarizer) complete(ctx context.Context, model, systemPrompt, userPrompt string) (string, error) {
+	return s.chat(ctx, model, []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(systemPrompt),
+		openai.UserMessage(userPrompt),
+	})
+}
 
+// chat sends a chat completion request with the given messages and returns the response text.
+func (s *OpenAICompatibleSummarizer) chat(ctx context.Context, model string, messages []openai.ChatCompletionMessageParamUnion) (string, error) {
 	chatCompletion, err := s.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(summarizerSystemPrompt),
-			openai.UserMessage(userPrompt),
-		},
-		Model:       openai.ChatModel(s.model),
+		Messages:    messages,
+		Model:       openai.ChatModel(model),
 		Temperature: openai.Float(1.0),
 	})
 	if err != nil {
@@ -85,3 +302,55 @@ func (s *OpenAICompatibleSummarizer) SummarizeText(ctx context.Context, title, t
 
 	return chatCompletion.Choices[0].Message.Content, nil
 }
+
+// stream sends a chat completion request with the given messages, calling onText with every piece of
+// the response as it arrives, and returns the whole response text.
+func (s *OpenAICompatibleSummarizer) stream(ctx context.Context, model string, messages []openai.ChatCompletionMessageParamUnion, onText func(text string)) (string, error) {
+	stream := s.client.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
+		Messages:    messages,
+		Model:       openai.ChatModel(model),
+		Temperature: openai.Float(1.0),
+	})
+	defer stream.Close()
+
+	var text strings.Builder
+	received := false
+	for stream.Next() {
+		chunk := stream.Current()
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		received = true
+		if delta := chunk.Choices[0].Delta.Content; delta != "" {
+			text.WriteString(delta)
+			onText(delta)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return "", err
+	}
+
+	if !received {
+		return "", errors.New("no response from LLM")
+	}
+	return text.String(), nil
+}
+
+// videoPrompt introduces the video at the start of a prompt.
+func videoPrompt(video Video) string {
+	return "Video Title: " + video.Title + "\n" + chapterList(video.Chapters)
+}
+
+// chapterList lists the chapters of a video for the prompt, so the summary can follow the video's structure.
+func chapterList(chapters []transcript.Chapter) string {
+	if len(chapters) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Chapters:\n")
+	for _, c := range chapters {
+		fmt.Fprintf(&b, "%s %s\n", transcript.FormatClock(c.Start), c.Title)
+	}
+	return b.String()
+}
@@ -0,0 +1,246 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeChatServer is an OpenAI-compatible chat completions endpoint that records the prompts
+// and answers with respond.
+type fakeChatServer struct {
+	mu      sync.Mutex
+	prompts []chatPrompt
+	respond func(p chatPrompt) string
+}
+
+type chatPrompt struct {
+	Model  string
+	System string
+	User   string   // The last user message
+	Roles  []string // Roles of all messages
+	Stream bool
+}
+
+func (f *fakeChatServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Model    string `json:"model"`
+		Stream   bool   `json:"stream"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p := chatPrompt{Model: request.Model, Stream: request.Stream}
+	for _, m := range request.Messages {
+		p.Roles = append(p.Roles, m.Role)
+		switch m.Role {
+		case "system":
+			p.System = m.Content
+		case "user":
+			p.User = m.Content
+		}
+	}
+
+	f.mu.Lock()
+	f.prompts = append(f.prompts, p)
+	f.mu.Unlock()
+
+	if request.Stream {
+		// Stream the response word by word as server-sent events
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range strings.SplitAfter(f.respond(p), " ") {
+			data, _ := json.Marshal(map[string]any{
+				"id":      "chatcmpl-test",
+				"object":  "chat.completion.chunk",
+				"created": 0,
+				"model":   "test",
+				"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": word}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   "test",
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]any{"role": "assistant", "content": f.respond(p)},
+		}},
+	})
+}
+
+func newTestSummarizer(t *testing.T, server *fakeChatServer, chunkTokens, chunkOverlap int) Summarizer {
+	t.Helper()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	summarizer, err := NewSummarizer(Config{
+		Endpoint:     ts.URL,
+		Model:        "test",
+		ChunkTokens:  chunkTokens,
+		ChunkOverlap: chunkOverlap,
+	})
+	if err != nil {
+		t.Fatalf("NewSummarizer: %v", err)
+	}
+	return summarizer
+}
+
+func TestSummarizeTranscriptSingleRequest(t *testing.T) {
+	server := &fakeChatServer{respond: func(chatPrompt) string { return "The summary" }}
+	summarizer := newTestSummarizer(t, server, 1000, 0)
+
+	summary, err := summarizer.SummarizeTranscript(context.Background(), Video{Title: "Short"}, testTranscript(5), SummaryOptions{})
+	if err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	if summary.Text != "The summary" || summary.Style != DefaultStyle || summary.Model != "test" || summary.PromptHash == "" {
+		t.Errorf("summary = %+v, want the response in the default style by the configured model", summary)
+	}
+	if len(server.prompts) != 1 || !strings.Contains(server.prompts[0].User, "seg e..") {
+		t.Errorf("prompts = %+v, want a single prompt with the whole transcript", server.prompts)
+	}
+}
+
+func TestSummarizeTranscriptMapReduce(t *testing.T) {
+	server := &fakeChatServer{}
+	server.respond = func(p chatPrompt) string {
+		switch p.System {
+		case chunkSystemPrompt:
+			return "summary of a part"
+		case combineSystemPrompt:
+			return "combined"
+		default:
+			return "final"
+		}
+	}
+	// 20 segments of 3 tokens in chunks of 12 tokens
+	summarizer := newTestSummarizer(t, server, 12, 0)
+
+	summary, err := summarizer.SummarizeTranscript(context.Background(), Video{Title: "Long"}, testTranscript(20), SummaryOptions{})
+	if err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	if summary.Text != "final" {
+		t.Errorf("summary = %q, want the result of the final request", summary.Text)
+	}
+
+	var chunkPrompts, combinePrompts int
+	for _, p := range server.prompts {
+		switch p.System {
+		case chunkSystemPrompt:
+			chunkPrompts++
+		case combineSystemPrompt:
+			combinePrompts++
+		}
+	}
+	if chunkPrompts != 5 {
+		t.Errorf("summarized %d chunks, want 5", chunkPrompts)
+	}
+	if combinePrompts == 0 {
+		t.Error("chunk summaries that do not fit into a chunk were not combined")
+	}
+
+	final := server.prompts[len(server.prompts)-1]
+	if final.System != summarizerSystemPrompt {
+		t.Errorf("last request used system prompt %q, want the summarizer prompt", final.System)
+	}
+}
+
+func TestSummarizeTranscriptChunkSummaries(t *testing.T) {
+	server := &fakeChatServer{}
+	server.respond = func(p chatPrompt) string {
+		if p.System == chunkSystemPrompt {
+			return "x"
+		}
+		return "final"
+	}
+	summarizer := newTestSummarizer(t, server, 30, 0)
+
+	if _, err := summarizer.SummarizeTranscript(context.Background(), Video{Title: "Long"}, testTranscript(20), SummaryOptions{}); err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	// Short chunk summaries fit into the final prompt without combining them first
+	final := server.prompts[len(server.prompts)-1]
+	for i := 1; i <= 2; i++ {
+		if heading := fmt.Sprintf("Part %d of 2", i); !strings.Contains(final.User, heading) {
+			t.Errorf("final prompt does not contain %q: %q", heading, final.User)
+		}
+	}
+	if len(server.prompts) != 3 {
+		t.Errorf("sent %d requests, want 2 chunks and the final one", len(server.prompts))
+	}
+}
+
+func TestNewSummarizerChunkValidation(t *testing.T) {
+	for _, cfg := range []Config{
+		{Endpoint: "http://localhost", Model: "m", ChunkTokens: -1},
+		{Endpoint: "http://localhost", Model: "m", ChunkTokens: 100, ChunkOverlap: 50},
+	} {
+		if _, err := NewSummarizer(cfg); err == nil {
+			t.Errorf("NewSummarizer(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
+
+func TestSummarizeTranscriptModel(t *testing.T) {
+	server := &fakeChatServer{respond: func(chatPrompt) string { return "summary" }}
+	summarizer := newTestSummarizer(t, server, 12, 0)
+
+	summary, err := summarizer.SummarizeTranscript(context.Background(), Video{Title: "Long"}, testTranscript(20), SummaryOptions{Model: "other"})
+	if err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	if summary.Model != "other" {
+		t.Errorf("Model = %q, want the requested model", summary.Model)
+	}
+	for _, p := range server.prompts {
+		if p.Model != "other" {
+			t.Errorf("request used model %q, want the requested model for every request", p.Model)
+		}
+	}
+}
+
+func TestSummarizeTranscriptStream(t *testing.T) {
+	server := &fakeChatServer{}
+	server.respond = func(p chatPrompt) string {
+		if p.System == chunkSystemPrompt {
+			return "summary of a part"
+		}
+		return "The final summary"
+	}
+	summarizer := newTestSummarizer(t, server, 30, 0)
+
+	var pieces []string
+	summary, err := summarizer.SummarizeTranscript(context.Background(), Video{Title: "Long"}, testTranscript(20), SummaryOptions{
+		OnText: func(text string) { pieces = append(pieces, text) },
+	})
+	if err != nil {
+		t.Fatalf("SummarizeTranscript: %v", err)
+	}
+	if summary.Text != "The final summary" || strings.Join(pieces, "") != summary.Text || len(pieces) != 3 {
+		t.Errorf("summary = %q streamed as %q, want the final response piece by piece", summary.Text, pieces)
+	}
+	for i, p := range server.prompts {
+		if final := i == len(server.prompts)-1; p.Stream != final {
+			t.Errorf("request %d streamed = %t, want only the final request streamed", i, p.Stream)
+		}
+	}
+}
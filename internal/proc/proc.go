@@ -0,0 +1,22 @@
+// Package proc runs external tools such as yt-dlp and ffmpeg, tied to a context.
+package proc
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// waitDelay is how long Wait waits for the output of a killed process to be closed,
+// e.g. by grandchildren that inherited it, before giving up on it.
+const waitDelay = 5 * time.Second
+
+// Command returns a command that is killed together with all of its children when ctx is done.
+// The process is started in its own process group where supported, so tools that spawn
+// helpers (yt-dlp runs ffmpeg) don't leave orphaned processes behind.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
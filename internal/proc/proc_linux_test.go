@@ -0,0 +1,55 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processExited reports whether the process is gone or a zombie waiting to be reaped.
+func processExited(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	// The state follows the command name in parentheses, e.g. "123 (sleep) Z ..."
+	_, fields, _ := strings.Cut(string(stat), ") ")
+	return strings.HasPrefix(fields, "Z") || strings.HasPrefix(fields, "X")
+}
+
+func TestCommandKillsProcessGroupOnTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	ctx, cancel := WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// The shell starts a child that keeps running unless the whole group is killed
+	cmd := Command(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	start := time.Now()
+	out, err := cmd.Output()
+	elapsed := time.Since(start)
+
+	err = TimeoutError(ctx, timeout, err)
+	if err == nil || !strings.HasPrefix(err.Error(), "timed out after 200ms") {
+		t.Errorf("error = %v, want a timeout error", err)
+	}
+	// Without the group kill the child would keep stdout open until waitDelay
+	if elapsed >= waitDelay {
+		t.Errorf("command returned after %s, want it killed on timeout", elapsed)
+	}
+
+	pid, convErr := strconv.Atoi(strings.TrimSpace(string(out)))
+	if convErr != nil {
+		t.Fatalf("child PID %q: %v", out, convErr)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !processExited(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d is still running", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
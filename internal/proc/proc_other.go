@@ -0,0 +1,8 @@
+//go:build !unix
+
+package proc
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are not supported, the process alone is killed on cancellation.
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,31 @@
+package proc
+
+import (
+	"context"
+	"io"
+)
+
+// Runner runs external commands. The fetch and ffmpeg packages run their tools through a Runner,
+// so tests can replace the real binaries with a fake.
+type Runner interface {
+	// Run runs the named program with args, writing its output to stdout and stderr
+	// (io.Discard to drop it). It returns once the program exits or ctx is done.
+	Run(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error
+}
+
+// ExecRunner runs commands as processes with Command.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+	cmd := Command(ctx, name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// RunnerFunc adapts a function to a Runner.
+type RunnerFunc func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error
+
+func (f RunnerFunc) Run(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+	return f(ctx, name, args, stdout, stderr)
+}
@@ -0,0 +1,33 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StageTimeouts limits how long each stage of processing a video may take before it fails.
+// A zero value means the stage is not limited.
+type StageTimeouts struct {
+	Metadata      time.Duration
+	Download      time.Duration // Also applies to fetching captions
+	Transcription time.Duration
+	Summary       time.Duration
+}
+
+// WithTimeout returns a context for a stage, limited to timeout if it is positive.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// TimeoutError explains the error of a stage that ran out of time, as killed tools only report "signal: killed".
+func TimeoutError(stageCtx context.Context, timeout time.Duration, err error) error {
+	if err != nil && errors.Is(stageCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return err
+}
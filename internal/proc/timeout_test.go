@@ -0,0 +1,42 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("context without a timeout has a deadline")
+	}
+
+	ctx, cancel = WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Hour {
+		t.Errorf("deadline = %v, %v, want one within an hour", deadline, ok)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	killed := errors.New("signal: killed")
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	err := TimeoutError(expired, time.Minute, killed)
+	if !errors.Is(err, killed) || err.Error() != "timed out after 1m0s: signal: killed" {
+		t.Errorf("TimeoutError = %v, want the timeout explained", err)
+	}
+	if err := TimeoutError(expired, time.Minute, nil); err != nil {
+		t.Errorf("TimeoutError without an error = %v, want nil", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := TimeoutError(cancelled, time.Minute, killed); err != killed {
+		t.Errorf("TimeoutError of a cancelled stage = %v, want the error unchanged", err)
+	}
+}
@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+var (
+	// ErrNotFound is returned when a video is not in the queue
+	ErrNotFound = errors.New("video not found in queue")
+	// ErrInvalidState is returned when an operation is not allowed in the video's current status
+	ErrInvalidState = errors.New("operation not allowed in current status")
+	// ErrAlreadyQueued is returned when adding a video that is already in the queue
+	ErrAlreadyQueued = errors.New("already in queue")
+)
+
+// runningJob holds the cancel function of a job that is currently being processed.
+type runningJob struct {
+	cancel context.CancelFunc
+}
+
+// runningJobs is keyed by video ID and guarded by queueMutex.
+var runningJobs = make(map[string]*runningJob)
+
+// JobContext returns a context for processing the given video that is cancelled
+// when the video is cancelled. The returned function must be called once processing
+// is finished.
+func JobContext(ctx context.Context, id string) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	job := &runningJob{cancel: cancel}
+
+	queueMutex.Lock()
+	runningJobs[id] = job
+	queueMutex.Unlock()
+
+	return jobCtx, func() {
+		queueMutex.Lock()
+		// The video may have been retried and picked up by another worker in the meantime
+		if runningJobs[id] == job {
+			delete(runningJobs, id)
+		}
+		queueMutex.Unlock()
+		cancel()
+	}
+}
+
+// IsFailed reports whether the status is one of the failure statuses.
+func (s VideoStatus) IsFailed() bool {
+	switch s {
+	case VideoStatusFailed, VideoStatusMetadataFailed, VideoStatusDownloadFailed, VideoStatusTranscriptionFailed, VideoStatusSummaryFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// findItem returns the queue item with the given ID. Must be called with queueMutex held.
+func findItem(id string) (int, *VideoInfo) {
+	for i, item := range transcriptionQueue {
+		if item.ID == id {
+			return i, item
+		}
+	}
+	return -1, nil
+}
+
+// cancelRunning stops the processing of a video if it is running. Must be called with queueMutex held.
+func cancelRunning(id string) {
+	if job, ok := runningJobs[id]; ok {
+		job.cancel()
+		delete(runningJobs, id)
+	}
+}
+
+// Cancel stops a pending or running video. Running yt-dlp and ffmpeg processes are killed.
+func Cancel(id string) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	if err := setStatus(item, VideoStatusCancelled, ""); err != nil {
+		return err
+	}
+
+	cancelRunning(id)
+	persistOrLog()
+	return nil
+}
+
+// Retry puts a failed, cancelled or completed video back into the queue.
+// With summaryOnly set the existing transcript and summaries are kept and only the summarization stage
+// runs again, adding another summary.
+func Retry(id string, summaryOnly bool) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	return requeue(item, summaryOnly)
+}
+
+// RequestSummary puts a finished video back into the queue to add a summary of its transcript
+// written with the given style and model, empty for the server defaults.
+func RequestSummary(id string, style string, model string) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	if err := checkRequeue(item, true); err != nil {
+		return err
+	}
+	item.SummaryStyle = style
+	item.SummaryModel = model
+	return requeue(item, true)
+}
+
+// checkRequeue returns an error if the video cannot be put back into the queue. Must be called with queueMutex held.
+func checkRequeue(item *VideoInfo, summaryOnly bool) error {
+	if item.Status != VideoStatusCompleted && item.Status != VideoStatusCancelled && !item.Status.IsFailed() {
+		return fmt.Errorf("%w: cannot retry %s video", ErrInvalidState, item.Status)
+	}
+	if summaryOnly && item.Transcript.IsEmpty() {
+		return fmt.Errorf("%w: video has no transcript to summarize", ErrInvalidState)
+	}
+	return nil
+}
+
+// requeue puts the video back into the queue, see Retry. Must be called with queueMutex held.
+func requeue(item *VideoInfo, summaryOnly bool) error {
+	if err := checkRequeue(item, summaryOnly); err != nil {
+		return err
+	}
+	if err := setStatus(item, VideoStatusPending, ""); err != nil {
+		return err
+	}
+	if !summaryOnly {
+		item.Transcript = transcript.Transcript{}
+		item.Summaries = nil
+		item.Conversation = nil
+	}
+	persistOrLog()
+	notifyPending()
+	return nil
+}
+
+// Remove deletes a video from the queue. Running videos must be cancelled first, as their
+// worker may still be using the video's files.
+func Remove(id string) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	i, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	if item.Status.IsInProgress() {
+		return fmt.Errorf("%w: cannot remove %s video, cancel it first", ErrInvalidState, item.Status)
+	}
+
+	transcriptionQueue = append(transcriptionQueue[:i], transcriptionQueue[i+1:]...)
+	persistOrLog()
+	return nil
+}
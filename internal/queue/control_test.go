@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// addCompleted adds a video that went through every stage and has a question asked about it.
+func addCompleted(t *testing.T, videoID string) string {
+	t.Helper()
+	videoInfo, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: videoID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := videoInfo.ID
+	GetNext()
+	for _, step := range []error{
+		Transition(id, VideoStatusTranscribing, ""),
+		SetTranscript(id, transcript.FromText("Said")),
+		Transition(id, VideoStatusSummarizing, ""),
+		AddSummary(id, Summary{Text: "Summary"}),
+		Transition(id, VideoStatusCompleted, ""),
+		AddExchange(id, Exchange{Question: "What was said?", Answer: "Said"}),
+	} {
+		if step != nil {
+			t.Fatal(step)
+		}
+	}
+	return id
+}
+
+func TestCancel(t *testing.T) {
+	openTestQueue(t)
+	videoInfo, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetNext()
+	ctx, done := JobContext(context.Background(), videoInfo.ID)
+	defer done()
+
+	if err := Cancel(videoInfo.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("job context was not cancelled")
+	}
+	if got := Get(videoInfo.ID).Status; got != VideoStatusCancelled {
+		t.Errorf("status = %s, want cancelled", got)
+	}
+
+	if err := Cancel(videoInfo.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("second Cancel error = %v, want ErrInvalidState", err)
+	}
+	if err := Cancel("youtube-missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel of unknown video error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	openTestQueue(t)
+	id := addCompleted(t, "abc123")
+
+	if err := Retry(id, true); err != nil {
+		t.Fatalf("Retry summary only: %v", err)
+	}
+	got := Get(id)
+	if got.Status != VideoStatusPending || got.Transcript.IsEmpty() || len(got.Summaries) != 1 || len(got.Conversation) != 1 {
+		t.Errorf("video = %+v, want it pending with its transcript, summary and conversation", got)
+	}
+	if err := Retry(id, false); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Retry of pending video error = %v, want ErrInvalidState", err)
+	}
+
+	if err := Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := Retry(id, false); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	got = Get(id)
+	if got.Status != VideoStatusPending || !got.Transcript.IsEmpty() || got.Summaries != nil || got.Conversation != nil {
+		t.Errorf("video = %+v, want it pending without results", got)
+	}
+
+	if err := Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := Retry(id, true); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("summary retry without transcript error = %v, want ErrInvalidState", err)
+	}
+	if err := Retry("youtube-missing", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Retry of unknown video error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	openTestQueue(t)
+	videoInfo, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetNext()
+	ctx, done := JobContext(context.Background(), videoInfo.ID)
+	defer done()
+
+	if err := Remove(videoInfo.ID); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("Remove of running video error = %v, want ErrInvalidState", err)
+	}
+	if Get(videoInfo.ID) == nil || ctx.Err() != nil {
+		t.Fatal("running video was removed or cancelled")
+	}
+
+	if err := Cancel(videoInfo.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(videoInfo.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if Get(videoInfo.ID) != nil || len(GetAll()) != 0 {
+		t.Error("video is still in the queue")
+	}
+	if err := Remove(videoInfo.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Remove error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestInvalidTransitions(t *testing.T) {
+	openTestQueue(t)
+	id := addCompleted(t, "abc123")
+
+	for _, status := range []VideoStatus{VideoStatusDownloading, VideoStatusProcessing, VideoStatusCompleted} {
+		if err := Transition(id, status, ""); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("Transition from completed to %s error = %v, want ErrInvalidState", status, err)
+		}
+	}
+	if err := Cancel(id); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Cancel of completed video error = %v, want ErrInvalidState", err)
+	}
+	if got := Get(id); got.Status != VideoStatusCompleted || len(got.History) != 5 {
+		t.Errorf("video = %+v, want it completed with an unchanged history", got)
+	}
+}
@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// JobID builds the identity of a job from the yt-dlp extractor and the video ID, e.g. "youtube-dQw4w9WgXcQ".
+// Video IDs are only unique per site and may contain any characters, so characters that are not
+// safe in URL paths are replaced and a short hash of the original ID keeps the result unique.
+func JobID(extractor, videoID string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, videoID)
+	if safe != videoID {
+		sum := sha256.Sum256([]byte(videoID))
+		safe += "-" + hex.EncodeToString(sum[:4])
+	}
+
+	return strings.ToLower(extractor) + "-" + safe
+}
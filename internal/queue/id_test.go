@@ -0,0 +1,23 @@
+package queue
+
+import "testing"
+
+func TestJobID(t *testing.T) {
+	tests := []struct {
+		extractor string
+		videoID   string
+		want      string
+	}{
+		{"youtube", "dQw4w9WgXcQ", "youtube-dQw4w9WgXcQ"},
+		{"Vimeo", "123456", "vimeo-123456"},
+		{"generic", "a.b_c-d", "generic-a.b_c-d"},
+		// IDs differing only in unsafe characters stay distinct
+		{"generic", "a/b?c", "generic-a_b_c-9e3e4499"},
+		{"generic", "a?b/c", "generic-a_b_c-04de8217"},
+	}
+	for _, tt := range tests {
+		if got := JobID(tt.extractor, tt.videoID); got != tt.want {
+			t.Errorf("JobID(%q, %q) = %q, want %q", tt.extractor, tt.videoID, got, tt.want)
+		}
+	}
+}
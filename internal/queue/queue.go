@@ -1,8 +1,15 @@
 package queue
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"log"
+	"slices"
 	"sync"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
 )
 
 type VideoStatus string
@@ -21,79 +28,282 @@ const (
 	VideoStatusSummaryFailed       VideoStatus = "summary_failed"
 	VideoStatusCompleted           VideoStatus = "completed"
 	VideoStatusFailed              VideoStatus = "failed"
+	VideoStatusCancelled           VideoStatus = "cancelled"
 )
 
 // VideoInfo holds all information about a video in the transcription queue.
 type VideoInfo struct {
-	VideoURL      string // The original user-supplied YouTube URL
-	VideoID       string
+	ID            string // Identifies the job, see JobID
+	VideoURL      string // The original user-supplied URL
+	Extractor     string // yt-dlp extractor of the video, e.g. "youtube" or "vimeo"
+	VideoID       string // ID of the video on its site, only unique per extractor
 	Title         string
 	Duration      string
 	UploadDate    string
 	Status        VideoStatus
+	Progress      *Progress `json:"-"` // Progress of the current stage, nil if unknown. Not persisted, see SetProgress.
+	Details       VideoDetails
 	AudioFilePath string
-	Transcript    string
-	Summary       string
+	SourceFile    string                   // Uploaded media file, transcribed instead of downloading VideoURL
+	WorkDir       string                   // Temporary directory used while processing the video
+	CaptionPolicy transcript.CaptionPolicy // Whether to use existing captions, empty for the server default
+	SummaryStyle  string                   // Prompt style of the next summary, empty for the server default
+	SummaryModel  string                   // LLM model of the next summary, empty for the server default
+	Transcript    transcript.Transcript
+	Summaries     []Summary  // Oldest first
+	Conversation  []Exchange // Questions about the transcript, oldest first
 	Error         string
+	History       []StatusChange // Status transitions, oldest first
+
+	// PartialSummary is the text of the summary being written while summarizing, see SetPartialSummary.
+	// Like Progress, it is not persisted.
+	PartialSummary string `json:"-"`
+
+	// LegacySummary is the summary of jobs saved before a job could have several, moved to Summaries by Open.
+	LegacySummary string `json:"Summary,omitempty"`
+}
+
+// Summary is a summary of the transcript of a video written by the LLM.
+type Summary struct {
+	Style      string // Prompt style the summary was written with
+	Model      string
+	PromptHash string // Identifies the prompts, see llm.Summary
+	CreatedAt  time.Time
+	Text       string
+}
+
+// Exchange is a question about the transcript of a video and the answer of the LLM.
+type Exchange struct {
+	Question  string
+	Answer    string
+	Model     string
+	Citations []time.Duration // Times in the video the answer refers to
+	AskedAt   time.Time
+}
+
+// VideoDetails holds the extended metadata of a video, fetched when its processing starts.
+type VideoDetails struct {
+	Channel      string
+	ChannelURL   string
+	Description  string
+	Tags         []string
+	Chapters     []transcript.Chapter
+	ThumbnailURL string
+	ViewCount    int64
+	Language     string
+	OriginalURL  string
+}
+
+// Progress reports how far the current stage of a video is.
+type Progress struct {
+	Percent float64       // 0 to 100
+	Speed   float64       // Download speed in bytes per second, 0 if unknown or not downloading
+	ETA     time.Duration // Estimated time remaining, 0 if unknown
+}
+
+// clone returns a copy of the video that does not share any slices with the original.
+func (v *VideoInfo) clone() *VideoInfo {
+	c := *v
+	if v.Progress != nil {
+		progress := *v.Progress
+		c.Progress = &progress
+	}
+	c.Transcript.Segments = slices.Clone(v.Transcript.Segments)
+	c.Summaries = slices.Clone(v.Summaries)
+	c.Conversation = slices.Clone(v.Conversation)
+	for i := range c.Conversation {
+		c.Conversation[i].Citations = slices.Clone(v.Conversation[i].Citations)
+	}
+	c.History = slices.Clone(v.History)
+	c.Details.Tags = slices.Clone(v.Details.Tags)
+	c.Details.Chapters = slices.Clone(v.Details.Chapters)
+	return &c
 }
 
 // NewVideoInfo is a simplified struct for adding new videos to the queue.
 type NewVideoInfo struct {
 	VideoURL   string
+	Extractor  string
 	VideoID    string
 	Title      string
 	Duration   string
 	UploadDate string
+	// Optional, the worker's default policy is used when empty
+	CaptionPolicy transcript.CaptionPolicy
+	// Optional, the worker's default style is used when empty
+	SummaryStyle string
+	// Set for uploaded files, which skip metadata fetching and downloading
+	SourceFile string
 }
 
 var (
 	transcriptionQueue []*VideoInfo
 	queueMutex         sync.Mutex
+	queueStore         Store
+	// pendingSignal is closed and replaced whenever a video becomes pending,
+	// waking up every worker blocked in WaitNext.
+	pendingSignal chan struct{}
+	// changedSignal is closed and replaced whenever a video changes, see Changed.
+	changedSignal chan struct{}
 )
 
 func init() {
 	transcriptionQueue = make([]*VideoInfo, 0)
+	queueStore = &MemoryStore{}
+	pendingSignal = make(chan struct{})
+	changedSignal = make(chan struct{})
+}
+
+// Changed returns a channel that is closed at the next change of a video, e.g. to push its state to a browser.
+func Changed() <-chan struct{} {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	return changedSignal
+}
+
+// notifyChanged wakes up everyone waiting for a change. Must be called with queueMutex held.
+func notifyChanged() {
+	close(changedSignal)
+	changedSignal = make(chan struct{})
+}
+
+// notifyPending wakes up all workers waiting for a pending video. Must be called with queueMutex held.
+func notifyPending() {
+	close(pendingSignal)
+	pendingSignal = make(chan struct{})
+}
+
+// Open replaces the queue contents with the items loaded from the given store
+// and persists all further changes to it.
+func Open(store Store) error {
+	items, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	queueStore = store
+	transcriptionQueue = make([]*VideoInfo, 0, len(items))
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		// Jobs saved before the extractor was recorded are YouTube videos
+		if item.ID == "" {
+			item.Extractor = "youtube"
+			item.ID = JobID(item.Extractor, item.VideoID)
+		}
+		if item.LegacySummary != "" {
+			item.Summaries = append(item.Summaries, Summary{Text: item.LegacySummary})
+			item.LegacySummary = ""
+		}
+		transcriptionQueue = append(transcriptionQueue, item)
+	}
+	notifyPending()
+	return nil
+}
+
+// persist saves the current queue to the store. Must be called with queueMutex held.
+func persist() error {
+	return queueStore.Save(transcriptionQueue)
+}
+
+// persistOrLog saves the current queue after a change, logging a failure instead of returning it.
+// Must be called with queueMutex held.
+func persistOrLog() {
+	if err := persist(); err != nil {
+		log.Printf("Error saving queue: %v", err)
+	}
+	notifyChanged()
 }
 
 // Add attempts to fetch video metadata and adds it to the queue.
-// It does NOT download the audio file itself.
+// It does NOT download the audio file itself. A copy of the job is returned, also along with
+// the error if the video is already queued.
 func Add(initialInfo NewVideoInfo) (*VideoInfo, error) {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 
-	// Check for existing VideoID
+	extractor := cmp.Or(initialInfo.Extractor, "generic")
+	id := JobID(extractor, initialInfo.VideoID)
+
+	// Check for existing job
 	for _, item := range transcriptionQueue {
-		if item.VideoID == initialInfo.VideoID {
-			return item, fmt.Errorf("video %s already in queue", initialInfo.VideoID)
+		if item.ID == id {
+			return item.clone(), fmt.Errorf("video %s %w", id, ErrAlreadyQueued)
 		}
 	}
 
 	finalInfo := &VideoInfo{
+		ID:            id,
 		VideoURL:      initialInfo.VideoURL,
+		Extractor:     extractor,
 		VideoID:       initialInfo.VideoID,
 		Title:         initialInfo.Title,
 		Duration:      initialInfo.Duration,
 		UploadDate:    initialInfo.UploadDate,
+		CaptionPolicy: initialInfo.CaptionPolicy,
+		SummaryStyle:  initialInfo.SummaryStyle,
+		SourceFile:    initialInfo.SourceFile,
 		Status:        VideoStatusPending, // Initial status
 		AudioFilePath: "",
-		Transcript:    "",
-		Summary:       "",
+		Transcript:    transcript.Transcript{},
 		Error:         "",
+		History: []StatusChange{
+			{Status: VideoStatusPending, Time: time.Now()},
+		},
 	}
 
 	transcriptionQueue = append(transcriptionQueue, finalInfo)
-	return finalInfo, nil
+	if err := persist(); err != nil {
+		transcriptionQueue = transcriptionQueue[:len(transcriptionQueue)-1]
+		return nil, fmt.Errorf("failed to save queue: %w", err)
+	}
+	notifyPending()
+	return finalInfo.clone(), nil
 }
 
-// GetNext finds the next "pending" video, sets its status to "processing", and returns it.
+// GetNext finds the next "pending" video, sets its status to "processing", and returns a copy of it.
+// It is safe to call from multiple workers, each pending video is handed out only once.
 func GetNext() *VideoInfo {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 
+	return takeNextPending()
+}
+
+// WaitNext is like GetNext, but blocks until a pending video is available or the context is done.
+func WaitNext(ctx context.Context) (*VideoInfo, error) {
+	for {
+		queueMutex.Lock()
+		item := takeNextPending()
+		signal := pendingSignal
+		queueMutex.Unlock()
+
+		if item != nil {
+			return item, nil
+		}
+
+		select {
+		case <-signal:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// takeNextPending marks the first pending video as processing. Must be called with queueMutex held.
+func takeNextPending() *VideoInfo {
 	for _, item := range transcriptionQueue {
 		if item.Status == VideoStatusPending {
-			item.Status = VideoStatusProcessing
-			return item
+			if err := setStatus(item, VideoStatusProcessing, ""); err != nil {
+				log.Printf("Error taking video %s from queue: %v", item.ID, err)
+				continue
+			}
+			persistOrLog()
+			return item.clone()
 		}
 	}
 
@@ -101,35 +311,46 @@ func GetNext() *VideoInfo {
 	return nil
 }
 
-// UpdateItem updates the status and optionally the error message and transcript of a video.
-func UpdateItem(videoID string, status VideoStatus, errorMessage string, transcript string, summary string) {
+// SetWorkDir sets the temporary working directory for a given video.
+func SetWorkDir(id string, workDir string) {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 
 	for _, item := range transcriptionQueue {
-		if item.VideoID == videoID {
-			item.Status = status
-			item.Error = errorMessage
-			item.Transcript = transcript
-			item.Summary = summary
+		if item.ID == id {
+			item.WorkDir = workDir
+			persistOrLog()
 			return
 		}
 	}
 }
 
 // SetAudioPath sets the audio file path for a given video.
-func SetAudioPath(videoID string, audioPath string) {
+func SetAudioPath(id string, audioPath string) {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 
 	for _, item := range transcriptionQueue {
-		if item.VideoID == videoID {
+		if item.ID == id {
 			item.AudioFilePath = audioPath
+			persistOrLog()
 			return
 		}
 	}
 }
 
+// Get returns a copy of the video with the given ID, or nil if it is not in the queue.
+func Get(id string) *VideoInfo {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return nil
+	}
+	return item.clone()
+}
+
 // GetAll returns a copy of the current queue in LIFO order.
 func GetAll() []*VideoInfo {
 	queueMutex.Lock()
@@ -139,8 +360,7 @@ func GetAll() []*VideoInfo {
 	queueCopy := make([]*VideoInfo, length)
 
 	for i, item := range transcriptionQueue {
-		itemCopy := *item
-		queueCopy[length-1-i] = &itemCopy
+		queueCopy[length-1-i] = item.clone()
 	}
 	return queueCopy
 }
@@ -150,4 +370,5 @@ func ClearQueue() {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 	transcriptionQueue = make([]*VideoInfo, 0)
+	persistOrLog()
 }
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdd(t *testing.T) {
+	openTestQueue(t)
+
+	added, err := Add(NewVideoInfo{VideoURL: "https://vimeo.com/1", Extractor: "vimeo", VideoID: "1", Title: "First"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if added.ID != "vimeo-1" || added.Status != VideoStatusPending || len(added.History) != 1 {
+		t.Errorf("added = %+v, want a pending job", added)
+	}
+
+	// The returned job is a copy
+	added.Title = "Changed"
+	added.History[0].Status = VideoStatusCompleted
+
+	existing, err := Add(NewVideoInfo{Extractor: "vimeo", VideoID: "1", Title: "Again"})
+	if !errors.Is(err, ErrAlreadyQueued) {
+		t.Fatalf("duplicate Add error = %v, want ErrAlreadyQueued", err)
+	}
+	if existing.Title != "First" || existing.History[0].Status != VideoStatusPending {
+		t.Errorf("existing = %+v, want the job unchanged by the copy", existing)
+	}
+	existing.Title = "Changed"
+	if got := Get("vimeo-1"); got.Title != "First" {
+		t.Errorf("queued title = %q, want it unchanged by the copy", got.Title)
+	}
+
+	if generic, err := Add(NewVideoInfo{VideoID: "1"}); err != nil || generic.ID != "generic-1" {
+		t.Errorf("Add without extractor = %+v, %v, want a generic job", generic, err)
+	}
+}
+
+func TestGetReturnsCopy(t *testing.T) {
+	openTestQueue(t)
+	id := addCompleted(t, "abc123")
+
+	got := Get(id)
+	got.Transcript.Segments[0].Text = "Changed"
+	got.Summaries[0].Text = "Changed"
+	got.History[0].Status = VideoStatusFailed
+
+	stored := Get(id)
+	if stored.Transcript.Text() != "Said" || stored.Summaries[0].Text != "Summary" || stored.History[0].Status != VideoStatusPending {
+		t.Errorf("video = %+v, want it unchanged by the copy", stored)
+	}
+}
+
+func TestWaitNextWakesOnAdd(t *testing.T) {
+	openTestQueue(t)
+
+	got := make(chan *VideoInfo, 1)
+	go func() {
+		videoInfo, err := WaitNext(context.Background())
+		if err != nil {
+			t.Errorf("WaitNext: %v", err)
+		}
+		got <- videoInfo
+	}()
+
+	// Give WaitNext time to find the queue empty and block
+	time.Sleep(20 * time.Millisecond)
+	if _, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case videoInfo := <-got:
+		if videoInfo == nil || videoInfo.ID != "youtube-abc123" || videoInfo.Status != VideoStatusProcessing {
+			t.Errorf("WaitNext = %+v, want the added video taken for processing", videoInfo)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitNext did not return after Add")
+	}
+}
+
+func TestWaitNextContextDone(t *testing.T) {
+	openTestQueue(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := WaitNext(ctx)
+		errs <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("WaitNext error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitNext did not return after the context was cancelled")
+	}
+}
+
+func TestGetNextConcurrent(t *testing.T) {
+	openTestQueue(t)
+	const jobs = 50
+	for i := range jobs {
+		if _, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	taken := make(map[string]int)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for videoInfo := GetNext(); videoInfo != nil; videoInfo = GetNext() {
+				mu.Lock()
+				taken[videoInfo.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(taken) != jobs {
+		t.Errorf("%d jobs taken, want %d", len(taken), jobs)
+	}
+	for id, n := range taken {
+		if n != 1 {
+			t.Errorf("%s taken %d times, want once", id, n)
+		}
+	}
+}
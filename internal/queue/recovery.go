@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+)
+
+// RecoveryPolicy decides what happens to jobs that were interrupted by a restart.
+type RecoveryPolicy string
+
+const (
+	// RecoveryPolicyRequeue puts interrupted jobs back into the pending state
+	RecoveryPolicyRequeue RecoveryPolicy = "requeue"
+	// RecoveryPolicyFail marks interrupted jobs as failed
+	RecoveryPolicyFail RecoveryPolicy = "fail"
+)
+
+// ParseRecoveryPolicy validates a recovery policy name.
+func ParseRecoveryPolicy(name string) (RecoveryPolicy, error) {
+	switch policy := RecoveryPolicy(name); policy {
+	case RecoveryPolicyRequeue, RecoveryPolicyFail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown recovery policy %q (expected %q or %q)", name, RecoveryPolicyRequeue, RecoveryPolicyFail)
+	}
+}
+
+// IsInProgress reports whether the status belongs to a job that is being worked on.
+func (s VideoStatus) IsInProgress() bool {
+	switch s {
+	case VideoStatusFetchingMetadata, VideoStatusProcessing, VideoStatusDownloading, VideoStatusTranscribing, VideoStatusSummarizing:
+		return true
+	default:
+		return false
+	}
+}
+
+// RecoverInterrupted applies the policy to every job left in an in-progress status,
+// e.g. after the server died mid-job. It returns copies of the affected jobs as they
+// were before recovery, so the caller can clean up their working directories.
+func RecoverInterrupted(policy RecoveryPolicy) []VideoInfo {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	var recovered []VideoInfo
+	for _, item := range transcriptionQueue {
+		if !item.Status.IsInProgress() {
+			continue
+		}
+
+		recovered = append(recovered, *item)
+
+		var err error
+		switch policy {
+		case RecoveryPolicyFail:
+			// Partial results (e.g. the transcript of a video interrupted while summarizing) are kept
+			err = setStatus(item, FailureStatus(item.Status), fmt.Sprintf("Interrupted by server restart while %s", item.Status))
+		default:
+			// Stored results are complete, so they are kept whatever the stage: the worker
+			// reuses an existing transcript and adds the redone summary to the earlier ones
+			err = setStatus(item, VideoStatusPending, "")
+		}
+		if err != nil {
+			log.Printf("Error recovering video %s: %v", item.ID, err)
+		}
+		item.AudioFilePath = ""
+		item.WorkDir = ""
+	}
+
+	if len(recovered) > 0 {
+		persistOrLog()
+		notifyPending()
+	}
+	return recovered
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// addInterrupted adds a video that was left in the given stage, with a transcript,
+// a summary and a conversation if withResults is set.
+func addInterrupted(t *testing.T, videoID string, stage VideoStatus, withResults bool) string {
+	t.Helper()
+	videoInfo, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: videoID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetNext()
+	if stage != VideoStatusProcessing {
+		if err := Transition(videoInfo.ID, stage, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetWorkDir(videoInfo.ID, "/tmp/work-"+videoID)
+	SetAudioPath(videoInfo.ID, "/tmp/work-"+videoID+"/audio.m4a")
+
+	if withResults {
+		queueMutex.Lock()
+		_, item := findItem(videoInfo.ID)
+		item.Transcript = transcript.FromText("Said")
+		item.Summaries = []Summary{{Text: "Summary"}}
+		item.Conversation = []Exchange{{Question: "What was said?", Answer: "Said"}}
+		queueMutex.Unlock()
+	}
+	return videoInfo.ID
+}
+
+func TestRecoverInterrupted(t *testing.T) {
+	tests := []struct {
+		policy      RecoveryPolicy
+		stage       VideoStatus
+		withResults bool
+		wantStatus  VideoStatus
+	}{
+		{RecoveryPolicyRequeue, VideoStatusDownloading, false, VideoStatusPending},
+		{RecoveryPolicyRequeue, VideoStatusSummarizing, true, VideoStatusPending},
+		// Summary-only retries wait for a summary slot before they are summarizing
+		{RecoveryPolicyRequeue, VideoStatusProcessing, true, VideoStatusPending},
+		{RecoveryPolicyFail, VideoStatusTranscribing, false, VideoStatusTranscriptionFailed},
+		{RecoveryPolicyFail, VideoStatusSummarizing, true, VideoStatusSummaryFailed},
+		{RecoveryPolicyFail, VideoStatusFetchingMetadata, false, VideoStatusMetadataFailed},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+"/"+string(tt.stage), func(t *testing.T) {
+			store := openTestQueue(t)
+			id := addInterrupted(t, "abc123", tt.stage, tt.withResults)
+			finished, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "done"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recovered := RecoverInterrupted(tt.policy)
+			if len(recovered) != 1 || recovered[0].ID != id || recovered[0].Status != tt.stage || recovered[0].WorkDir != "/tmp/work-abc123" {
+				t.Fatalf("recovered = %+v, want the interrupted job as it was", recovered)
+			}
+
+			got := Get(id)
+			if got.Status != tt.wantStatus || got.WorkDir != "" || got.AudioFilePath != "" {
+				t.Errorf("video = %+v, want %s without its work dir", got, tt.wantStatus)
+			}
+			if tt.withResults && (got.Transcript.Text() != "Said" || len(got.Summaries) != 1 || len(got.Conversation) != 1) {
+				t.Errorf("video = %+v, want its transcript, summary and conversation kept", got)
+			}
+			if Get(finished.ID).Status != VideoStatusPending {
+				t.Errorf("pending job was changed to %s", Get(finished.ID).Status)
+			}
+
+			// The recovery is persisted
+			items, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if items[0].Status != tt.wantStatus {
+				t.Errorf("saved status = %s, want %s", items[0].Status, tt.wantStatus)
+			}
+			if tt.withResults && len(items[0].Summaries) != 1 {
+				t.Errorf("saved summaries = %+v, want the earlier summary", items[0].Summaries)
+			}
+
+			if again := RecoverInterrupted(tt.policy); len(again) != 0 {
+				t.Errorf("second recovery = %+v, want nothing left to recover", again)
+			}
+		})
+	}
+}
+
+func TestParseRecoveryPolicy(t *testing.T) {
+	if policy, err := ParseRecoveryPolicy("fail"); err != nil || policy != RecoveryPolicyFail {
+		t.Errorf("ParseRecoveryPolicy(fail) = %q, %v", policy, err)
+	}
+	if _, err := ParseRecoveryPolicy("retry"); err == nil {
+		t.Error("ParseRecoveryPolicy accepted an unknown policy")
+	}
+}
@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// StatusChange records a single status transition of a video.
+type StatusChange struct {
+	Status VideoStatus
+	Error  string
+	Time   time.Time
+}
+
+// allowedTransitions lists the statuses each status may move to.
+// In-progress statuses may go back to pending when an interrupted job is requeued.
+var allowedTransitions = map[VideoStatus][]VideoStatus{
+	VideoStatusPending:             {VideoStatusProcessing, VideoStatusFetchingMetadata, VideoStatusCancelled},
+	VideoStatusFetchingMetadata:    {VideoStatusDownloading, VideoStatusMetadataFailed, VideoStatusFailed, VideoStatusPending, VideoStatusCancelled},
+	VideoStatusProcessing:          {VideoStatusFetchingMetadata, VideoStatusDownloading, VideoStatusTranscribing, VideoStatusSummarizing, VideoStatusFailed, VideoStatusPending, VideoStatusCancelled},
+	VideoStatusDownloading:         {VideoStatusTranscribing, VideoStatusDownloadFailed, VideoStatusFailed, VideoStatusPending, VideoStatusCancelled},
+	VideoStatusTranscribing:        {VideoStatusSummarizing, VideoStatusTranscriptionFailed, VideoStatusFailed, VideoStatusPending, VideoStatusCancelled},
+	VideoStatusSummarizing:         {VideoStatusCompleted, VideoStatusSummaryFailed, VideoStatusFailed, VideoStatusPending, VideoStatusCancelled},
+	VideoStatusCompleted:           {VideoStatusPending},
+	VideoStatusFailed:              {VideoStatusPending},
+	VideoStatusMetadataFailed:      {VideoStatusPending},
+	VideoStatusDownloadFailed:      {VideoStatusPending},
+	VideoStatusTranscriptionFailed: {VideoStatusPending},
+	VideoStatusSummaryFailed:       {VideoStatusPending},
+	VideoStatusCancelled:           {VideoStatusPending},
+}
+
+// CanTransition reports whether a video may move from one status to another.
+func CanTransition(from, to VideoStatus) bool {
+	return slices.Contains(allowedTransitions[from], to)
+}
+
+// FailureStatus returns the stage-specific failure status for an in-progress status.
+func FailureStatus(stage VideoStatus) VideoStatus {
+	switch stage {
+	case VideoStatusFetchingMetadata:
+		return VideoStatusMetadataFailed
+	case VideoStatusDownloading:
+		return VideoStatusDownloadFailed
+	case VideoStatusTranscribing:
+		return VideoStatusTranscriptionFailed
+	case VideoStatusSummarizing:
+		return VideoStatusSummaryFailed
+	default:
+		return VideoStatusFailed
+	}
+}
+
+// setStatus validates and applies a status transition and records it in the history.
+// Must be called with queueMutex held.
+func setStatus(item *VideoInfo, status VideoStatus, errorMessage string) error {
+	if !CanTransition(item.Status, status) {
+		return fmt.Errorf("%w: cannot move video %s from %s to %s", ErrInvalidState, item.ID, item.Status, status)
+	}
+
+	item.Status = status
+	item.Error = errorMessage
+	item.Progress = nil
+	item.PartialSummary = ""
+	item.History = append(item.History, StatusChange{
+		Status: status,
+		Error:  errorMessage,
+		Time:   time.Now(),
+	})
+	return nil
+}
+
+// Transition moves a video to a new status, recording the error message for failure statuses.
+// It returns ErrInvalidState if the transition is not allowed, e.g. because the video was cancelled.
+func Transition(id string, status VideoStatus, errorMessage string) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	if err := setStatus(item, status, errorMessage); err != nil {
+		return err
+	}
+	persistOrLog()
+	return nil
+}
+
+// SetMetadata stores the metadata of a video that is having its metadata fetched.
+// Empty basic fields keep their current value, as they may have been known when the video was added.
+func SetMetadata(id string, title, duration, uploadDate string, details VideoDetails) error {
+	return setResult(id, VideoStatusFetchingMetadata, func(item *VideoInfo) {
+		item.Title = cmp.Or(title, item.Title)
+		item.Duration = cmp.Or(duration, item.Duration)
+		item.UploadDate = cmp.Or(uploadDate, item.UploadDate)
+		item.Details = details
+	})
+}
+
+// SetProgress updates the progress of a video while it is in the given stage.
+// It is not persisted, as the progress is reset once the video leaves the stage.
+func SetProgress(id string, stage VideoStatus, progress Progress) error {
+	return setTransient(id, stage, func(item *VideoInfo) {
+		item.Progress = &progress
+	})
+}
+
+// SetTranscript stores the transcript of a video that is being transcribed.
+func SetTranscript(id string, t transcript.Transcript) error {
+	return setResult(id, VideoStatusTranscribing, func(item *VideoInfo) {
+		item.Transcript = t
+	})
+}
+
+// AddSummary adds a summary to a video that is being summarized.
+func AddSummary(id string, summary Summary) error {
+	return setResult(id, VideoStatusSummarizing, func(item *VideoInfo) {
+		item.Summaries = append(item.Summaries, summary)
+		item.PartialSummary = ""
+	})
+}
+
+// SetPartialSummary stores the text written so far of the summary of a video that is being summarized.
+// It is not persisted, as it is discarded once the video leaves the stage.
+func SetPartialSummary(id string, text string) error {
+	return setTransient(id, VideoStatusSummarizing, func(item *VideoInfo) {
+		item.PartialSummary = text
+	})
+}
+
+// AddExchange adds a question and its answer to the conversation about the transcript of a video.
+func AddExchange(id string, exchange Exchange) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	// The transcript may have been cleared by a retry while the question was answered
+	if item.Transcript.IsEmpty() {
+		return fmt.Errorf("%w: video has no transcript", ErrInvalidState)
+	}
+
+	item.Conversation = append(item.Conversation, exchange)
+	persistOrLog()
+	return nil
+}
+
+// ClearConversation removes all questions about the transcript of a video.
+func ClearConversation(id string) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+
+	item.Conversation = nil
+	persistOrLog()
+	return nil
+}
+
+// setResult applies a stage result, but only while the video is in that stage.
+func setResult(id string, stage VideoStatus, apply func(item *VideoInfo)) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	if item.Status != stage {
+		return fmt.Errorf("%w: video %s is %s, not %s", ErrInvalidState, id, item.Status, stage)
+	}
+
+	apply(item)
+	persistOrLog()
+	return nil
+}
+
+// setTransient is like setResult, but only notifies about the change without persisting the queue.
+func setTransient(id string, stage VideoStatus, apply func(item *VideoInfo)) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	_, item := findItem(id)
+	if item == nil {
+		return ErrNotFound
+	}
+	if item.Status != stage {
+		return fmt.Errorf("%w: video %s is %s, not %s", ErrInvalidState, id, item.Status, stage)
+	}
+
+	apply(item)
+	notifyChanged()
+	return nil
+}
@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to VideoStatus
+		want     bool
+	}{
+		{VideoStatusPending, VideoStatusProcessing, true},
+		{VideoStatusPending, VideoStatusCompleted, false},
+		{VideoStatusProcessing, VideoStatusFetchingMetadata, true},
+		{VideoStatusFetchingMetadata, VideoStatusDownloading, true},
+		{VideoStatusFetchingMetadata, VideoStatusMetadataFailed, true},
+		{VideoStatusDownloading, VideoStatusTranscribing, true},
+		{VideoStatusDownloading, VideoStatusSummarizing, false},
+		{VideoStatusTranscribing, VideoStatusSummarizing, true},
+		{VideoStatusSummarizing, VideoStatusCompleted, true},
+		{VideoStatusSummarizing, VideoStatusDownloading, false},
+		{VideoStatusTranscribing, VideoStatusPending, true},
+		{VideoStatusCompleted, VideoStatusPending, true},
+		{VideoStatusCompleted, VideoStatusSummarizing, false},
+		{VideoStatusCancelled, VideoStatusCompleted, false},
+		{VideoStatusDownloadFailed, VideoStatusCancelled, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedTransitions(t *testing.T) {
+	for from, targets := range allowedTransitions {
+		for _, to := range targets {
+			if _, ok := allowedTransitions[to]; !ok {
+				t.Errorf("%s may move to %s, which has no transitions", from, to)
+			}
+		}
+		// Every finished job can be retried and every in-progress job can be cancelled or requeued
+		if !from.IsInProgress() && from != VideoStatusPending {
+			if !slices.Equal(targets, []VideoStatus{VideoStatusPending}) {
+				t.Errorf("finished status %s may move to %v, want only pending", from, targets)
+			}
+			continue
+		}
+		if !slices.Contains(targets, VideoStatusCancelled) {
+			t.Errorf("%s cannot be cancelled", from)
+		}
+		if from.IsInProgress() && (!slices.Contains(targets, VideoStatusPending) || !slices.Contains(targets, FailureStatus(from))) {
+			t.Errorf("%s cannot be requeued or fail", from)
+		}
+	}
+}
+
+func TestTransitionRecordsHistory(t *testing.T) {
+	openTestQueue(t)
+	videoInfo, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetNext()
+
+	if err := Transition(videoInfo.ID, VideoStatusCompleted, ""); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("invalid transition error = %v, want ErrInvalidState", err)
+	}
+	if err := Transition(videoInfo.ID, VideoStatusFailed, "boom"); err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+	if err := Transition("youtube-missing", VideoStatusPending, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown video error = %v, want ErrNotFound", err)
+	}
+
+	got := Get(videoInfo.ID)
+	var statuses []VideoStatus
+	for _, change := range got.History {
+		statuses = append(statuses, change.Status)
+	}
+	if !slices.Equal(statuses, []VideoStatus{VideoStatusPending, VideoStatusProcessing, VideoStatusFailed}) {
+		t.Errorf("history = %v, want only the allowed transitions", statuses)
+	}
+	if got.Status != VideoStatusFailed || got.Error != "boom" || got.History[2].Error != "boom" {
+		t.Errorf("video = %+v, want it failed with the error", got)
+	}
+}
+
+func TestSetResultRequiresStage(t *testing.T) {
+	openTestQueue(t)
+	if _, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTranscript("youtube-abc123", transcript.FromText("Too early")); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("SetTranscript while pending error = %v, want ErrInvalidState", err)
+	}
+	if !GetAll()[0].Transcript.IsEmpty() {
+		t.Error("transcript was stored outside of the transcribing stage")
+	}
+}
+
+func TestFailureStatus(t *testing.T) {
+	tests := map[VideoStatus]VideoStatus{
+		VideoStatusFetchingMetadata: VideoStatusMetadataFailed,
+		VideoStatusDownloading:      VideoStatusDownloadFailed,
+		VideoStatusTranscribing:     VideoStatusTranscriptionFailed,
+		VideoStatusSummarizing:      VideoStatusSummaryFailed,
+		VideoStatusProcessing:       VideoStatusFailed,
+	}
+	for stage, want := range tests {
+		if got := FailureStatus(stage); got != want {
+			t.Errorf("FailureStatus(%s) = %s, want %s", stage, got, want)
+		}
+	}
+}
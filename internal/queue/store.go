@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"path/filepath"
+
+	"github.com/exler/yt-transcribe/internal/storage"
+)
+
+const queueFileName = "queue.json"
+
+// Store persists the contents of the transcription queue.
+type Store interface {
+	// Load returns all previously saved items in insertion order.
+	Load() ([]*VideoInfo, error)
+	// Save replaces the stored items with the given snapshot.
+	Save(items []*VideoInfo) error
+}
+
+// MemoryStore does not persist anything, the queue is lost on restart.
+type MemoryStore struct{}
+
+func (m *MemoryStore) Load() ([]*VideoInfo, error) {
+	return nil, nil
+}
+
+func (m *MemoryStore) Save(items []*VideoInfo) error {
+	return nil
+}
+
+// FileStore persists the queue as a JSON document inside a data directory.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a file-backed store, creating the data directory if needed.
+func NewFileStore(dataDir string) (*FileStore, error) {
+	if err := storage.EnsureDir(dataDir); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		path: filepath.Join(dataDir, queueFileName),
+	}, nil
+}
+
+func (f *FileStore) Load() ([]*VideoInfo, error) {
+	var items []*VideoInfo
+	if err := storage.ReadJSON(f.path, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (f *FileStore) Save(items []*VideoInfo) error {
+	return storage.WriteJSON(f.path, items)
+}
@@ -0,0 +1,205 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/exler/yt-transcribe/internal/transcript"
+)
+
+// openTestQueue opens an empty queue persisted in a temporary directory and returns its store.
+func openTestQueue(t *testing.T) *FileStore {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	if err := Open(store); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() {
+		Open(&MemoryStore{})
+		ClearQueue()
+	})
+	return store
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	store := openTestQueue(t)
+
+	if _, err := Add(NewVideoInfo{VideoURL: "https://youtu.be/abc123", Extractor: "youtube", VideoID: "abc123", Title: "A video"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := Add(NewVideoInfo{VideoURL: "https://youtu.be/def456", Extractor: "youtube", VideoID: "def456", Title: "Another video"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	GetNext()
+	if err := Transition("youtube-abc123", VideoStatusDownloading, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := Transition("youtube-abc123", VideoStatusTranscribing, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTranscript("youtube-abc123", transcript.FromText("The transcript")); err != nil {
+		t.Fatal(err)
+	}
+	want := GetAll()
+
+	// A new run loads the queue as it was saved after every change
+	reopened, err := NewFileStore(filepath.Dir(store.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Open(reopened); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	gotJSON, _ := json.Marshal(GetAll())
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("reopened queue = %s, want %s", gotJSON, wantJSON)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(store.path)); len(entries) != 1 {
+		t.Errorf("data directory has %d entries, want only the queue file", len(entries))
+	}
+}
+
+func TestFileStoreLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string // Contents of the queue file, none if empty
+		want    int
+		wantErr bool
+	}{
+		{name: "missing file", want: 0},
+		{name: "saved queue", data: `[{"VideoID":"abc123","Status":"pending"},{"VideoID":"def456","Status":"completed"}]`, want: 2},
+		{name: "null entries", data: `[null,{"VideoID":"abc123"}]`, want: 1},
+		{name: "invalid JSON", data: `[{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.data != "" {
+				if err := os.WriteFile(filepath.Join(dir, queueFileName), []byte(tt.data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store, err := NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				Open(&MemoryStore{})
+				ClearQueue()
+			})
+
+			err = Open(store)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := len(GetAll()); !tt.wantErr && got != tt.want {
+				t.Errorf("queue has %d videos, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFileStoreRequiresDir(t *testing.T) {
+	if _, err := NewFileStore(""); err == nil {
+		t.Error("NewFileStore without a data directory succeeded")
+	}
+}
+
+func TestOpenMigratesLegacyJobs(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"VideoID":"abc123","Title":"Old","Status":"completed","Transcript":"1\n00:00:00,000 --> 00:00:01,000\nHi\n","Summary":"Old summary"},null]`
+	if err := os.WriteFile(filepath.Join(dir, queueFileName), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Open(store); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() {
+		Open(&MemoryStore{})
+		ClearQueue()
+	})
+
+	got := Get("youtube-abc123")
+	if got == nil || got.Extractor != "youtube" {
+		t.Fatalf("video = %+v, want the legacy job identified as a YouTube video", got)
+	}
+	if len(got.Summaries) != 1 || got.Summaries[0].Text != "Old summary" || got.LegacySummary != "" {
+		t.Errorf("summaries = %+v, want the legacy summary", got.Summaries)
+	}
+	if got.Transcript.Text() != "Hi" {
+		t.Errorf("transcript = %+v, want the parsed legacy transcript", got.Transcript)
+	}
+	if len(GetAll()) != 1 {
+		t.Errorf("queue has %d videos, want null entries skipped", len(GetAll()))
+	}
+
+	// Adding the same video again finds the migrated job
+	if _, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"}); !errors.Is(err, ErrAlreadyQueued) {
+		t.Errorf("Add of the legacy video error = %v, want ErrAlreadyQueued", err)
+	}
+}
+
+// countingStore counts how often the queue is saved.
+type countingStore struct {
+	MemoryStore
+	saves int
+}
+
+func (c *countingStore) Save(items []*VideoInfo) error {
+	c.saves++
+	return nil
+}
+
+func TestTransientUpdatesAreNotSaved(t *testing.T) {
+	store := &countingStore{}
+	if err := Open(store); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Open(&MemoryStore{})
+		ClearQueue()
+	})
+	videoInfo, err := Add(NewVideoInfo{Extractor: "youtube", VideoID: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetNext()
+	if err := Transition(videoInfo.ID, VideoStatusSummarizing, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	saves := store.saves
+	for i := range 10 {
+		if err := SetPartialSummary(videoInfo.ID, strings.Repeat("word ", i)); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetProgress(videoInfo.ID, VideoStatusSummarizing, Progress{Percent: float64(i * 10)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.saves != saves {
+		t.Errorf("queue saved %d times for progress updates, want none", store.saves-saves)
+	}
+
+	// Progress is left out when the queue is saved for other changes
+	data, err := json.Marshal(Get(videoInfo.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "Progress") || strings.Contains(string(data), "PartialSummary") {
+		t.Errorf("saved video %s contains transient progress", data)
+	}
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type document struct {
+	Name  string
+	Items []int
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.json")
+	want := document{Name: "queue", Items: []int{1, 2, 3}}
+
+	if err := WriteJSON(path, want); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	// Replacing the file keeps only the latest version and no temporary files
+	want.Items = want.Items[:1]
+	if err := WriteJSON(path, want); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the document", len(entries))
+	}
+
+	var got document
+	if err := ReadJSON(path, &got); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{"missing file", "missing.json", false},
+		{"invalid JSON", "broken.json", true},
+		{"directory", ".", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := document{Name: "unchanged"}
+			err := ReadJSON(filepath.Join(dir, tt.file), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadJSON error = %v, want error %v", err, tt.wantErr)
+			}
+			if got.Name != "unchanged" {
+				t.Errorf("ReadJSON changed the value to %+v", got)
+			}
+		})
+	}
+}
+
+func TestWriteJSONMissingDir(t *testing.T) {
+	if err := WriteJSON(filepath.Join(t.TempDir(), "missing", "doc.json"), document{}); err == nil {
+		t.Error("WriteJSON into a missing directory succeeded")
+	}
+}
+
+func TestEnsureDir(t *testing.T) {
+	if err := EnsureDir(""); err == nil {
+		t.Error("EnsureDir without a directory succeeded")
+	}
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	if err := EnsureDir(dir); err != nil {
+		t.Fatalf("EnsureDir: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("directory was not created: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/fetch"
+	"github.com/exler/yt-transcribe/internal/queue"
+)
+
+// pollLatest is how many of the latest entries are listed on every poll.
+const pollLatest = 20
+
+// PlaylistLister lists the videos of a playlist or channel.
+// It is implemented by fetch.Downloader.
+type PlaylistLister interface {
+	ListPlaylist(ctx context.Context, url string, opts fetch.PlaylistOptions) (fetch.Playlist, error)
+}
+
+// Poller periodically checks subscriptions for new uploads and adds them to the queue.
+type Poller struct {
+	manager  *Manager
+	lister   PlaylistLister
+	interval time.Duration
+}
+
+// NewPoller creates a poller checking all subscriptions every interval.
+func NewPoller(manager *Manager, lister PlaylistLister, interval time.Duration) *Poller {
+	return &Poller{
+		manager:  manager,
+		lister:   lister,
+		interval: interval,
+	}
+}
+
+// Run polls all subscriptions right away and then on every interval until the context is done.
+func (p *Poller) Run(ctx context.Context) {
+	log.Printf("Subscription poller started, checking every %s...", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.PollAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Println("Subscription poller stopped")
+			return
+		}
+	}
+}
+
+// PollAll checks every subscription once.
+func (p *Poller) PollAll(ctx context.Context) {
+	subscriptions, err := p.manager.List()
+	if err != nil {
+		log.Printf("Error loading subscriptions: %v", err)
+		return
+	}
+
+	for _, s := range subscriptions {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := p.Poll(ctx, s.ID); err != nil {
+			log.Printf("Error checking subscription %s (%s): %v", s.ID, s.URL, err)
+		}
+	}
+}
+
+// Poll checks a single subscription and adds videos that were not seen before to the queue.
+// The first poll of a subscription only records the existing videos. It returns the number of added videos.
+func (p *Poller) Poll(ctx context.Context, id string) (int, error) {
+	subscriptions, err := p.manager.List()
+	if err != nil {
+		return 0, err
+	}
+	i := slices.IndexFunc(subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		return 0, ErrNotFound
+	}
+	s := subscriptions[i]
+
+	playlist, err := p.lister.ListPlaylist(ctx, s.URL, fetch.PlaylistOptions{Latest: pollLatest})
+	if err == nil && !playlist.IsPlaylist {
+		err = errors.New("URL is not a channel or playlist")
+	}
+	if err != nil {
+		updateErr := p.manager.Update(id, func(s *Subscription) {
+			s.LastCheckedAt = time.Now()
+			s.LastError = err.Error()
+		})
+		return 0, errors.Join(err, updateErr)
+	}
+
+	var newEntries []fetch.PlaylistEntry
+	for _, entry := range playlist.Entries {
+		if !slices.Contains(s.SeenVideoIDs, entry.VideoID) {
+			newEntries = append(newEntries, entry)
+		}
+	}
+
+	added := 0
+	seen := newEntries
+	var addErr error
+	if s.Seeded {
+		seen = nil
+		// Enqueue oldest first, channels list the latest uploads first
+		for _, entry := range slices.Backward(newEntries) {
+			_, err := queue.Add(queue.NewVideoInfo{
+				VideoURL:   entry.URL,
+				Extractor:  entry.Extractor,
+				VideoID:    entry.VideoID,
+				Title:      entry.Title,
+				Duration:   entry.Duration,
+				UploadDate: entry.UploadDate,
+			})
+			switch {
+			case err == nil:
+				log.Printf("Video added to queue from subscription %s: ID %s, Title: %s", id, entry.VideoID, entry.Title)
+				added++
+			case errors.Is(err, queue.ErrAlreadyQueued):
+				// Most likely the video was added manually
+				log.Printf("Skipping video %s from subscription %s: %v", entry.VideoID, id, err)
+			default:
+				// Not marked as seen, so the next poll tries again
+				log.Printf("Error adding video %s from subscription %s: %v", entry.VideoID, id, err)
+				if addErr == nil {
+					addErr = fmt.Errorf("failed to add video %s: %w", entry.VideoID, err)
+				}
+				continue
+			}
+			seen = append(seen, entry)
+		}
+	}
+
+	err = p.manager.Update(id, func(s *Subscription) {
+		if playlist.Title != "" {
+			s.Title = playlist.Title
+		}
+		for _, entry := range seen {
+			s.SeenVideoIDs = append(s.SeenVideoIDs, entry.VideoID)
+		}
+		s.Seeded = true
+		s.LastCheckedAt = time.Now()
+		s.LastError = ""
+		if addErr != nil {
+			s.LastError = addErr.Error()
+		}
+	})
+	return added, errors.Join(addErr, err)
+}
@@ -0,0 +1,170 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/fetch"
+	"github.com/exler/yt-transcribe/internal/queue"
+)
+
+// fakeLister lists the entries of a channel, latest first, like yt-dlp does.
+type fakeLister struct {
+	playlist fetch.Playlist
+	err      error
+}
+
+func (f *fakeLister) ListPlaylist(ctx context.Context, url string, opts fetch.PlaylistOptions) (fetch.Playlist, error) {
+	return f.playlist, f.err
+}
+
+// upload adds a new latest entry to the channel.
+func (f *fakeLister) upload(videoID string) {
+	entry := fetch.PlaylistEntry{Extractor: "youtube", VideoID: videoID, Title: "Video " + videoID, URL: "https://youtu.be/" + videoID}
+	f.playlist.Entries = slices.Insert(f.playlist.Entries, 0, entry)
+}
+
+// failingStore makes every change of the queue fail.
+type failingStore struct{}
+
+func (failingStore) Load() ([]*queue.VideoInfo, error) { return nil, nil }
+
+func (failingStore) Save(items []*queue.VideoInfo) error { return errors.New("disk full") }
+
+func newTestPoller(t *testing.T, lister PlaylistLister) (*Poller, *Manager, string) {
+	t.Helper()
+	queue.ClearQueue()
+	t.Cleanup(queue.ClearQueue)
+
+	manager := NewManager(&MemoryStore{})
+	s, err := manager.Add("https://www.youtube.com/@channel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPoller(manager, lister, time.Hour), manager, s.ID
+}
+
+func getSubscription(t *testing.T, manager *Manager, id string) Subscription {
+	t.Helper()
+	subscriptions, err := manager.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		t.Fatalf("subscription %s not found", id)
+	}
+	return subscriptions[i]
+}
+
+func queuedVideoIDs() []string {
+	var ids []string
+	for _, videoInfo := range queue.GetAll() {
+		ids = append(ids, videoInfo.VideoID)
+	}
+	return ids
+}
+
+func TestPollAddsNewUploads(t *testing.T) {
+	lister := &fakeLister{playlist: fetch.Playlist{Title: "Channel", IsPlaylist: true}}
+	lister.upload("old")
+	poller, manager, id := newTestPoller(t, lister)
+
+	// The first poll only records the existing videos
+	if added, err := poller.Poll(context.Background(), id); err != nil || added != 0 {
+		t.Fatalf("first Poll = %d, %v, want nothing added", added, err)
+	}
+	if s := getSubscription(t, manager, id); !s.Seeded || s.Title != "Channel" || !slices.Equal(s.SeenVideoIDs, []string{"old"}) {
+		t.Errorf("subscription = %+v, want it seeded with the existing video", s)
+	}
+
+	lister.upload("new1")
+	lister.upload("new2")
+	lister.upload("manual")
+	if _, err := queue.Add(queue.NewVideoInfo{Extractor: "youtube", VideoID: "manual"}); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := poller.Poll(context.Background(), id)
+	if err != nil || added != 2 {
+		t.Fatalf("Poll = %d, %v, want 2 added", added, err)
+	}
+	// GetAll lists the latest added video first
+	if ids := queuedVideoIDs(); !slices.Equal(ids, []string{"new2", "new1", "manual"}) {
+		t.Errorf("queued videos = %v, want the new uploads added oldest first", ids)
+	}
+	// Videos that were already queued count as seen
+	if s := getSubscription(t, manager, id); !slices.Equal(s.SeenVideoIDs, []string{"old", "new1", "new2", "manual"}) {
+		t.Errorf("SeenVideoIDs = %v, want every listed video", s.SeenVideoIDs)
+	}
+
+	if added, err := poller.Poll(context.Background(), id); err != nil || added != 0 {
+		t.Errorf("repeated Poll = %d, %v, want nothing added", added, err)
+	}
+}
+
+func TestPollRetriesFailedAdds(t *testing.T) {
+	lister := &fakeLister{playlist: fetch.Playlist{IsPlaylist: true}}
+	poller, manager, id := newTestPoller(t, lister)
+	if _, err := poller.Poll(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+
+	lister.upload("new")
+	if err := queue.Open(failingStore{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { queue.Open(&queue.MemoryStore{}) })
+	added, err := poller.Poll(context.Background(), id)
+	if err == nil || added != 0 {
+		t.Fatalf("Poll = %d, %v, want an error", added, err)
+	}
+	s := getSubscription(t, manager, id)
+	if slices.Contains(s.SeenVideoIDs, "new") {
+		t.Errorf("SeenVideoIDs = %v, want the video that failed to be added left unseen", s.SeenVideoIDs)
+	}
+	if s.LastError == "" {
+		t.Error("LastError is empty, want the failure")
+	}
+
+	if err := queue.Open(&queue.MemoryStore{}); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := poller.Poll(context.Background(), id); err != nil || added != 1 {
+		t.Fatalf("Poll after recovering = %d, %v, want the video added", added, err)
+	}
+	if s := getSubscription(t, manager, id); !slices.Contains(s.SeenVideoIDs, "new") || s.LastError != "" {
+		t.Errorf("subscription = %+v, want the video seen and no error", s)
+	}
+}
+
+func TestPollListingErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		lister *fakeLister
+	}{
+		{"listing fails", &fakeLister{err: errors.New("network down")}},
+		{"not a playlist", &fakeLister{playlist: fetch.Playlist{Entries: []fetch.PlaylistEntry{{VideoID: "abc"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poller, manager, id := newTestPoller(t, tt.lister)
+			if _, err := poller.Poll(context.Background(), id); err == nil {
+				t.Fatal("Poll succeeded, want an error")
+			}
+			if s := getSubscription(t, manager, id); s.Seeded || s.LastError == "" || s.LastCheckedAt.IsZero() {
+				t.Errorf("subscription = %+v, want the error recorded without seeding", s)
+			}
+		})
+	}
+}
+
+func TestPollUnknownSubscription(t *testing.T) {
+	poller, _, _ := newTestPoller(t, &fakeLister{})
+	if _, err := poller.Poll(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+}
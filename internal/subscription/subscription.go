@@ -0,0 +1,182 @@
+package subscription
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/exler/yt-transcribe/internal/storage"
+)
+
+const subscriptionsFileName = "subscriptions.json"
+
+// ErrNotFound is returned when a subscription does not exist
+var ErrNotFound = errors.New("subscription not found")
+
+// Subscription is a channel or playlist that is watched for new uploads.
+type Subscription struct {
+	ID    string
+	URL   string
+	Title string
+	// Set once the videos that existed when subscribing have been recorded,
+	// so only uploads published afterwards are transcribed.
+	Seeded bool
+	// IDs of all videos seen in the channel or playlist, used to detect new uploads.
+	SeenVideoIDs  []string
+	CreatedAt     time.Time
+	LastCheckedAt time.Time
+	LastError     string
+}
+
+// Store persists the subscriptions.
+type Store interface {
+	Load() ([]Subscription, error)
+	Save(subscriptions []Subscription) error
+}
+
+// MemoryStore keeps subscriptions in memory only, they are lost on restart.
+type MemoryStore struct {
+	subscriptions []Subscription
+}
+
+func (m *MemoryStore) Load() ([]Subscription, error) {
+	return slices.Clone(m.subscriptions), nil
+}
+
+func (m *MemoryStore) Save(subscriptions []Subscription) error {
+	m.subscriptions = slices.Clone(subscriptions)
+	return nil
+}
+
+// FileStore persists subscriptions as a JSON document next to the queue.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a file-backed store, creating the data directory if needed.
+func NewFileStore(dataDir string) (*FileStore, error) {
+	if err := storage.EnsureDir(dataDir); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		path: filepath.Join(dataDir, subscriptionsFileName),
+	}, nil
+}
+
+func (f *FileStore) Load() ([]Subscription, error) {
+	var subscriptions []Subscription
+	if err := storage.ReadJSON(f.path, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (f *FileStore) Save(subscriptions []Subscription) error {
+	return storage.WriteJSON(f.path, subscriptions)
+}
+
+// Manager manages the subscriptions. Every operation reads and writes the store,
+// so the CLI and a running server can work on the same data directory.
+type Manager struct {
+	store Store
+	mu    sync.Mutex
+}
+
+// NewManager creates a manager for the subscriptions in the given store.
+func NewManager(store Store) *Manager {
+	return &Manager{store: store}
+}
+
+// List returns all subscriptions.
+func (m *Manager) List() ([]Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.store.Load()
+}
+
+// Add subscribes to a channel or playlist URL.
+func (m *Manager) Add(url string) (Subscription, error) {
+	if url == "" {
+		return Subscription{}, errors.New("subscription URL is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions, err := m.store.Load()
+	if err != nil {
+		return Subscription{}, err
+	}
+	for _, s := range subscriptions {
+		if s.URL == url {
+			return s, fmt.Errorf("already subscribed to %s", url)
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Subscription{}, err
+	}
+	subscription := Subscription{
+		ID:        id,
+		URL:       url,
+		CreatedAt: time.Now(),
+	}
+
+	if err := m.store.Save(append(subscriptions, subscription)); err != nil {
+		return Subscription{}, err
+	}
+	return subscription, nil
+}
+
+// Remove unsubscribes from a channel or playlist.
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	return m.store.Save(slices.Delete(subscriptions, i, i+1))
+}
+
+// Update applies fn to the subscription with the given ID and saves the result.
+func (m *Manager) Update(id string, fn func(s *Subscription)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	fn(&subscriptions[i])
+	return m.store.Save(subscriptions)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate subscription ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,10 @@
+package transcript
+
+import "time"
+
+// Chapter is a titled section of a video, as defined by its uploader.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
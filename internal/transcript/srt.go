@@ -0,0 +1,106 @@
+package transcript
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSRT parses a SubRip (SRT) document, as written by the FFmpeg whisper filter, into a transcript.
+// Sequence numbers are optional and both "," and "." are accepted as millisecond separators.
+func ParseSRT(data string) (Transcript, error) {
+	data = strings.TrimPrefix(data, "\ufeff")
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+
+	var t Transcript
+	for _, block := range strings.Split(data, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+			continue
+		}
+
+		// Skip the sequence number if present
+		if !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 || !strings.Contains(lines[0], "-->") {
+			return Transcript{}, fmt.Errorf("invalid SRT block, missing timing line: %q", block)
+		}
+
+		start, end, err := parseTimingLine(lines[0])
+		if err != nil {
+			return Transcript{}, err
+		}
+
+		textLines := make([]string, 0, len(lines)-1)
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				textLines = append(textLines, line)
+			}
+		}
+
+		t.Segments = append(t.Segments, Segment{
+			Start: start,
+			End:   end,
+			Text:  strings.Join(textLines, " "),
+		})
+	}
+	return t, nil
+}
+
+// parseTimingLine parses a line like "00:00:01,000 --> 00:00:04,500".
+func parseTimingLine(line string) (time.Duration, time.Duration, error) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid SRT timing line: %q", line)
+	}
+
+	start, err := parseTimestamp(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	// Cue settings may follow the end timestamp
+	endFields := strings.Fields(parts[1])
+	if len(endFields) == 0 {
+		return 0, 0, fmt.Errorf("invalid SRT timing line: %q", line)
+	}
+	end, err := parseTimestamp(endFields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// parseTimestamp parses "HH:MM:SS,mmm", "HH:MM:SS.mmm" or "MM:SS.mmm".
+func parseTimestamp(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	value = strings.Replace(value, ",", ".", 1)
+
+	clock, fraction, _ := strings.Cut(value, ".")
+	fields := strings.Split(clock, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %q", value)
+	}
+
+	var d time.Duration
+	units := []time.Duration{time.Second, time.Minute, time.Hour}
+	for i := range fields {
+		n, err := strconv.Atoi(fields[len(fields)-1-i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp: %q", value)
+		}
+		d += time.Duration(n) * units[i]
+	}
+
+	if fraction != "" {
+		// Normalize to milliseconds, e.g. ".5" is 500ms
+		fraction = (fraction + "000")[:3]
+		millis, err := strconv.Atoi(fraction)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %q", value)
+		}
+		d += time.Duration(millis) * time.Millisecond
+	}
+	return d, nil
+}
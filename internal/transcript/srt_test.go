@@ -0,0 +1,63 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSRT(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Segment
+	}{
+		{
+			name: "sequence numbers and multi-line cues",
+			data: "1\n00:00:01,000 --> 00:00:04,500\nHello\nthere\n\n2\n00:00:04,500 --> 00:00:06,000\nGeneral Kenobi\n",
+			want: []Segment{
+				{Start: time.Second, End: 4500 * time.Millisecond, Text: "Hello there"},
+				{Start: 4500 * time.Millisecond, End: 6 * time.Second, Text: "General Kenobi"},
+			},
+		},
+		{
+			name: "no sequence numbers and dot separators",
+			data: "\ufeff00:00:01.5 --> 00:00:02.25\r\nShort\r\n",
+			want: []Segment{{Start: 1500 * time.Millisecond, End: 2250 * time.Millisecond, Text: "Short"}},
+		},
+		{
+			name: "timestamps without hours",
+			data: "01:02.000 --> 01:03.500\nMinutes\n",
+			want: []Segment{{Start: 62 * time.Second, End: 63500 * time.Millisecond, Text: "Minutes"}},
+		},
+		{
+			name: "hours past a day",
+			data: "25:00:00,000 --> 25:00:01,000\nLate\n",
+			want: []Segment{{Start: 25 * time.Hour, End: 25*time.Hour + time.Second, Text: "Late"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSRT(tt.data)
+			if err != nil {
+				t.Fatalf("ParseSRT: %v", err)
+			}
+			if !reflect.DeepEqual(got.Segments, tt.want) {
+				t.Errorf("segments = %+v, want %+v", got.Segments, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSRTErrors(t *testing.T) {
+	for _, data := range []string{
+		"1\nHello\n",
+		"00:00:01,000 --> \nHello\n",
+		"1 --> 2\nHello\n",
+		"00:00:aa,000 --> 00:00:02,000\nHello\n",
+	} {
+		if _, err := ParseSRT(data); err == nil {
+			t.Errorf("ParseSRT(%q) succeeded, want an error", data)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is a single timed piece of a transcript.
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Source is where a transcript came from.
+type Source string
+
+const (
+	SourceWhisper        Source = "whisper"
+	SourceManualCaptions Source = "manual_captions"
+	SourceAutoCaptions   Source = "auto_captions"
+)
+
+// Label returns a human readable name of the source.
+func (s Source) Label() string {
+	switch s {
+	case SourceWhisper:
+		return "Whisper"
+	case SourceManualCaptions:
+		return "Captions"
+	case SourceAutoCaptions:
+		return "Automatic captions"
+	default:
+		return "Unknown"
+	}
+}
+
+// CaptionPolicy decides whether existing captions are used instead of running Whisper.
+type CaptionPolicy string
+
+const (
+	// CaptionPolicyWhisper always transcribes the audio with Whisper
+	CaptionPolicyWhisper CaptionPolicy = "whisper"
+	// CaptionPolicyManual uses human-authored captions if available and falls back to Whisper
+	CaptionPolicyManual CaptionPolicy = "manual"
+	// CaptionPolicyAuto uses human-authored captions, then automatic captions and falls back to Whisper
+	CaptionPolicyAuto CaptionPolicy = "auto"
+)
+
+// ParseCaptionPolicy validates a caption policy name.
+func ParseCaptionPolicy(name string) (CaptionPolicy, error) {
+	switch policy := CaptionPolicy(name); policy {
+	case CaptionPolicyWhisper, CaptionPolicyManual, CaptionPolicyAuto:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown caption policy %q (expected %q, %q or %q)", name, CaptionPolicyWhisper, CaptionPolicyManual, CaptionPolicyAuto)
+	}
+}
+
+// Transcript is a transcription split into timed segments.
+type Transcript struct {
+	Segments []Segment
+	Source   Source
+}
+
+// IsEmpty reports whether the transcript has no text.
+func (t Transcript) IsEmpty() bool {
+	for _, segment := range t.Segments {
+		if segment.Text != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Duration returns the end time of the last segment.
+func (t Transcript) Duration() time.Duration {
+	if len(t.Segments) == 0 {
+		return 0
+	}
+	return t.Segments[len(t.Segments)-1].End
+}
+
+// Text returns the transcript as plain text, one segment per line.
+func (t Transcript) Text() string {
+	lines := make([]string, 0, len(t.Segments))
+	for _, segment := range t.Segments {
+		if segment.Text != "" {
+			lines = append(lines, segment.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SRT returns the transcript in the SubRip subtitle format.
+func (t Transcript) SRT() string {
+	var b strings.Builder
+	for i, segment := range t.Segments {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, FormatTimestamp(segment.Start, ","), FormatTimestamp(segment.End, ","), segment.Text)
+	}
+	return b.String()
+}
+
+// VTT returns the transcript in the WebVTT subtitle format.
+func (t Transcript) VTT() string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, segment := range t.Segments {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", FormatTimestamp(segment.Start, "."), FormatTimestamp(segment.End, "."), segment.Text)
+	}
+	return b.String()
+}
+
+// UnmarshalJSON decodes a transcript, also accepting the raw SRT string
+// that older versions stored for each video.
+func (t *Transcript) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		parsed, err := ParseSRT(raw)
+		if err != nil {
+			// Keep unparseable legacy text as a single untimed segment
+			parsed = FromText(raw)
+		}
+		*t = parsed
+		return nil
+	}
+
+	type plain Transcript // Avoid recursing into UnmarshalJSON
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*t = Transcript(decoded)
+	return nil
+}
+
+// FromText creates an untimed transcript from plain text.
+func FromText(text string) Transcript {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Transcript{}
+	}
+	return Transcript{Segments: []Segment{{Text: text}}}
+}
+
+// FormatTimestamp formats a duration as HH:MM:SS followed by the separator and milliseconds,
+// e.g. "00:01:02,500" for SRT or "00:01:02.500" for WebVTT.
+func FormatTimestamp(d time.Duration, millisSeparator string) string {
+	if d < 0 {
+		d = 0
+	}
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	millis := (d % time.Second) / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, millisSeparator, millis)
+}
+
+// FormatClock formats a duration as a short clock time, e.g. "1:02" or "1:02:03".
+func FormatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// ParseClock parses a clock time as formatted by FormatClock, e.g. "1:02" or "1:02:03".
+func ParseClock(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid clock time %q", value)
+	}
+
+	var d time.Duration
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && (n >= 60 || len(part) != 2)) {
+			return 0, fmt.Errorf("invalid clock time %q", value)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	return d * time.Second, nil
+}
@@ -0,0 +1,63 @@
+package transcript
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Transcript
+	}{
+		{
+			name: "segments",
+			data: `{"Segments":[{"Start":1000000000,"End":2000000000,"Text":"Hello"}],"Source":"auto_captions"}`,
+			want: Transcript{Segments: []Segment{{Start: time.Second, End: 2 * time.Second, Text: "Hello"}}, Source: SourceAutoCaptions},
+		},
+		{
+			name: "legacy SRT string",
+			data: `"1\n00:00:01,000 --> 00:00:02,000\nHello\n\n"`,
+			want: Transcript{Segments: []Segment{{Start: time.Second, End: 2 * time.Second, Text: "Hello"}}},
+		},
+		{
+			name: "legacy plain text",
+			data: `"  just text\n"`,
+			want: Transcript{Segments: []Segment{{Text: "just text"}}},
+		},
+		{
+			name: "legacy empty string",
+			data: `""`,
+			want: Transcript{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Transcript
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transcript = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	want := Transcript{Segments: []Segment{{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "Hi"}}, Source: SourceWhisper}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Transcript
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package transcript
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// vttTagPattern matches inline WebVTT tags like <c>, </c> or <00:00:01.500>.
+var vttTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ParseVTT parses a WebVTT document into a transcript. Styling tags are removed and lines
+// repeated from the previous cue, as found in YouTube's automatic captions, are dropped.
+func ParseVTT(data string) (Transcript, error) {
+	data = strings.TrimPrefix(data, "\ufeff")
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+
+	if !strings.HasPrefix(data, "WEBVTT") {
+		return Transcript{}, fmt.Errorf("invalid WebVTT document, missing header")
+	}
+
+	var t Transcript
+	var previousLines []string
+	for _, block := range strings.Split(data, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// Skip the header, comments, styles and regions
+		timing := slices.IndexFunc(lines, func(line string) bool { return strings.Contains(line, "-->") })
+		if timing < 0 {
+			continue
+		}
+
+		start, end, err := parseTimingLine(lines[timing])
+		if err != nil {
+			return Transcript{}, err
+		}
+
+		// Tags are removed from the whole cue text, as they may span several lines. Entities are
+		// decoded afterwards, so an escaped "&lt;" is kept as text instead of starting a tag.
+		text := vttTagPattern.ReplaceAllString(strings.Join(lines[timing+1:], "\n"), "")
+		text = html.UnescapeString(text)
+
+		var cueLines []string
+		var newLines []string
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			cueLines = append(cueLines, line)
+			if !slices.Contains(previousLines, line) {
+				newLines = append(newLines, line)
+			}
+		}
+		if len(cueLines) > 0 {
+			previousLines = cueLines
+		}
+		if len(newLines) == 0 {
+			continue
+		}
+
+		t.Segments = append(t.Segments, Segment{
+			Start: start,
+			End:   end,
+			Text:  strings.Join(newLines, " "),
+		})
+	}
+	return t, nil
+}
@@ -0,0 +1,73 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseVTT(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Segment
+	}{
+		{
+			name: "cue settings and identifiers",
+			data: "WEBVTT\n\nintro\n00:00:01.000 --> 00:00:02.000 align:start position:0%\nHello\n",
+			want: []Segment{{Start: time.Second, End: 2 * time.Second, Text: "Hello"}},
+		},
+		{
+			name: "header, notes and styles",
+			data: "\ufeffWEBVTT Kind: captions\r\nLanguage: en\r\n\r\nNOTE a comment\r\n\r\nSTYLE\r\n::cue { color: red }\r\n\r\n00:01.000 --> 00:02.500\r\nNo hours\r\n",
+			want: []Segment{{Start: time.Second, End: 2500 * time.Millisecond, Text: "No hours"}},
+		},
+		{
+			name: "multi-line cues",
+			data: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nFirst line\nsecond line\n",
+			want: []Segment{{Start: time.Second, End: 2 * time.Second, Text: "First line second line"}},
+		},
+		{
+			name: "tags",
+			data: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\n<v Speaker>Hello</v> <c.colorE5E5E5>wor<00:00:01.500><c>ld</c></c>\n",
+			want: []Segment{{Start: time.Second, End: 2 * time.Second, Text: "Hello world"}},
+		},
+		{
+			name: "tags spanning lines",
+			data: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello <c\n.yellow>there</c>\n",
+			want: []Segment{{Start: time.Second, End: 2 * time.Second, Text: "Hello there"}},
+		},
+		{
+			name: "entities",
+			data: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\n&gt;&gt; Tom &amp; Jerry <c>&lt;3</c>\n",
+			want: []Segment{{Start: time.Second, End: 2 * time.Second, Text: ">> Tom & Jerry <3"}},
+		},
+		{
+			name: "lines repeated from the previous cue",
+			data: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nrolling\n\n" +
+				"00:00:02.000 --> 00:00:02.010\nrolling\n\n" +
+				"00:00:02.010 --> 00:00:03.000\nrolling\ncaptions\n",
+			want: []Segment{
+				{Start: time.Second, End: 2 * time.Second, Text: "rolling"},
+				{Start: 2010 * time.Millisecond, End: 3 * time.Second, Text: "captions"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVTT(tt.data)
+			if err != nil {
+				t.Fatalf("ParseVTT: %v", err)
+			}
+			if !reflect.DeepEqual(got.Segments, tt.want) {
+				t.Errorf("segments = %+v, want %+v", got.Segments, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVTTMissingHeader(t *testing.T) {
+	if _, err := ParseVTT("00:00:01.000 --> 00:00:02.000\nHello\n"); err == nil {
+		t.Error("ParseVTT without a header succeeded")
+	}
+}